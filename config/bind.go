@@ -0,0 +1,390 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config
+
+import (
+	"os"
+	"fmt"
+	"reflect"
+	"strings"
+	"strconv"
+)
+
+// FieldError describes a single property that could not be bound.
+type FieldError struct {
+	Name string // the full property name
+	Msg  string
+}
+
+func (e *FieldError) String() string {
+	if e.Name == "" {
+		return e.Msg
+	}
+	return e.Name + ": " + e.Msg
+}
+
+// BindError is returned by Bind and lists every property that
+// could not be bound, rather than stopping at the first failure.
+type BindError struct {
+	Errors []*FieldError
+}
+
+func (e *BindError) String() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.String()
+	}
+	return "cannot bind properties: " + strings.Join(msgs, "; ")
+}
+
+var propertiesType = reflect.TypeOf((*Properties)(nil))
+
+// Bind stores the named property value in the value pointed to by target.
+// Maps are bound to structs and maps with string keys, arrays are bound
+// to slices. Struct fields are matched with map keys by the field name
+// or by a field tag of the form:
+//
+//	Port  int64    `config:"port,default=8080"`
+//	Hosts []string `config:"hosts,required"`
+//	Debug bool     `config:"-"`
+//
+// A field tagged "-" is ignored. A default is used when the key is not
+// present and is parsed according to the field type, with slices split
+// on commas. A required field without a default is reported if the key
+// is not present, including the fields of a nested struct whose key is
+// not present. Fields whose key is not present are otherwise left
+// unchanged. A field of type *Properties receives the raw sub properties.
+// Values from the environment overlay take the place of the values of
+// p, and are parsed according to the field type as by the getters,
+// and references in strings are expanded if interpolation is enabled.
+func (p *Properties) Bind(target interface{}, name ...interface{}) os.Error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return os.NewError(fmt.Sprint("bind target is not a non-nil pointer: ", reflect.TypeOf(target)))
+	}
+
+	path, err := parseName(name...)
+	if err != nil {
+		return err
+	}
+	prop, err := p.lookup(path)
+	if err != nil {
+		return err
+	}
+
+	p.rlock()
+	defer p.runlock()
+	b := &binder{p: p}
+	b.bind(v.Elem(), prop, path)
+	if len(b.errs) > 0 {
+		return &BindError{b.errs}
+	}
+	return nil
+}
+
+// binder collects the errors found while binding a property tree.
+type binder struct {
+	p    *Properties
+	errs []*FieldError
+}
+
+func (b *binder) fail(path []segment, msg string) {
+	b.errs = append(b.errs, &FieldError{formatPath(joinPath(b.p.path, path)), msg})
+}
+
+func (b *binder) mismatch(path []segment, t reflect.Type, prop interface{}) {
+	b.fail(path, fmt.Sprintf("cannot bind property of type '%s' to '%s'.", typeName(prop), t))
+}
+
+func (b *binder) bind(v reflect.Value, prop interface{}, path []segment) {
+	if e, ok := b.p.envLookup(joinPath(b.p.path, path)); ok {
+		prop = e
+	}
+	if b.p.interp {
+		var err os.Error
+		prop, err = b.p.interpolate(prop, path)
+		if err != nil {
+			b.fail(path, err.String())
+			return
+		}
+	}
+	if v.Type() == propertiesType {
+		v.Set(reflect.ValueOf(b.p.sub(prop, path)))
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if prop == nil {
+			v.Set(reflect.Zero(v.Type()))
+			return
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		b.bind(v.Elem(), prop, path)
+	case reflect.Interface:
+		if v.Type().NumMethod() > 0 {
+			b.mismatch(path, v.Type(), prop)
+		} else if prop == nil {
+			v.Set(reflect.Zero(v.Type()))
+		} else if e, ok := prop.(envValue); ok {
+			v.Set(reflect.ValueOf(string(e)))
+		} else {
+			v.Set(reflect.ValueOf(prop))
+		}
+	case reflect.Struct:
+		m, ok := prop.(map[string]interface{})
+		if !ok {
+			b.mismatch(path, v.Type(), prop)
+			return
+		}
+		b.bindStruct(v, m, path)
+	case reflect.Map:
+		m, ok := prop.(map[string]interface{})
+		if !ok || v.Type().Key().Kind() != reflect.String {
+			b.mismatch(path, v.Type(), prop)
+			return
+		}
+		mv := reflect.MakeMap(v.Type())
+		for key, elem := range m {
+			ev := reflect.New(v.Type().Elem()).Elem()
+			b.bind(ev, elem, appendPath(path, segment{key, false}))
+			kv := reflect.New(v.Type().Key()).Elem()
+			kv.SetString(key)
+			mv.SetMapIndex(kv, ev)
+		}
+		v.Set(mv)
+	case reflect.Slice:
+		a, ok := prop.([]interface{})
+		if e, isEnv := prop.(envValue); isEnv {
+			a, ok = envArray(e), true
+		}
+		if !ok {
+			b.mismatch(path, v.Type(), prop)
+			return
+		}
+		sv := reflect.MakeSlice(v.Type(), len(a), len(a))
+		for i, elem := range a {
+			b.bind(sv.Index(i), elem, appendPath(path, segment{strconv.Itoa(i), true}))
+		}
+		v.Set(sv)
+	default:
+		b.bindScalar(v, prop, path)
+	}
+}
+
+func (b *binder) bindStruct(v reflect.Value, m map[string]interface{}, path []segment) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		key, dflt, hasDflt, required := parseTag(f)
+		if key == "-" {
+			continue
+		}
+		fpath := appendPath(path, segment{key, false})
+		prop, ok := m[key]
+		if !ok {
+			prop, ok = b.p.envLookup(joinPath(b.p.path, fpath))
+		}
+		switch {
+		case ok:
+			b.bind(v.Field(i), prop, fpath)
+		case hasDflt:
+			err := bindString(v.Field(i), dflt)
+			if err != nil {
+				b.fail(fpath, "invalid default: "+err.String())
+			}
+		case required:
+			b.fail(fpath, "required property is missing.")
+		default:
+			b.missing(f.Type, fpath)
+		}
+	}
+}
+
+// missing reports the required fields of a struct of type t, and of
+// the structs it contains, whose key at path is not present. Fields
+// that are pointers are left nil, so their required fields are not
+// reported.
+func (b *binder) missing(t reflect.Type, path []segment) {
+	if t.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		key, _, hasDflt, required := parseTag(f)
+		if key == "-" || hasDflt {
+			continue
+		}
+		fpath := appendPath(path, segment{key, false})
+		if required {
+			b.fail(fpath, "required property is missing.")
+		} else {
+			b.missing(f.Type, fpath)
+		}
+	}
+}
+
+func (b *binder) bindScalar(v reflect.Value, prop interface{}, path []segment) {
+	switch v.Kind() {
+	case reflect.Bool:
+		if x, ok := b.p.toBool(prop); ok {
+			v.SetBool(x)
+			return
+		}
+	case reflect.String:
+		if x, ok := b.p.toString(prop); ok {
+			v.SetString(x)
+			return
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, ok := b.p.toNumber(prop); ok {
+			x, err := n.Int64()
+			if err != nil || v.OverflowInt(x) {
+				b.fail(path, fmt.Sprint("number cannot be stored in ", v.Type(), ": ", n))
+			} else {
+				v.SetInt(x)
+			}
+			return
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n, ok := b.p.toNumber(prop); ok {
+			x, err := n.Uint64()
+			if err != nil || v.OverflowUint(x) {
+				b.fail(path, fmt.Sprint("number cannot be stored in ", v.Type(), ": ", n))
+			} else {
+				v.SetUint(x)
+			}
+			return
+		}
+	case reflect.Float32, reflect.Float64:
+		if n, ok := b.p.toNumber(prop); ok {
+			x, err := n.Float64()
+			if err != nil || v.OverflowFloat(x) {
+				b.fail(path, fmt.Sprint("number cannot be stored in ", v.Type(), ": ", n))
+			} else {
+				v.SetFloat(x)
+			}
+			return
+		}
+	}
+	b.mismatch(path, v.Type(), prop)
+}
+
+// parseTag returns the property key and options from a struct field tag.
+// A default value extends to the next option, so it may contain commas.
+func parseTag(f reflect.StructField) (key, dflt string, hasDflt, required bool) {
+	tag := f.Tag.Get("config")
+	if tag == "" {
+		return f.Name, "", false, false
+	}
+	opts := strings.Split(tag, ",")
+	key = opts[0]
+	if key == "" {
+		key = f.Name
+	}
+	for _, opt := range opts[1:] {
+		switch {
+		case opt == "required":
+			required = true
+		case strings.HasPrefix(opt, "default="):
+			dflt = opt[len("default="):]
+			hasDflt = true
+		case hasDflt:
+			dflt += "," + opt
+		}
+	}
+	return
+}
+
+// bindString parses a default value from a tag into v.
+func bindString(v reflect.Value, s string) os.Error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return bindString(v.Elem(), s)
+	case reflect.Bool:
+		x, err := strconv.Atob(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(x)
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x, err := strconv.Atoi64(s)
+		if err != nil {
+			return err
+		}
+		if v.OverflowInt(x) {
+			return os.NewError(fmt.Sprint("number cannot be stored in ", v.Type(), ": ", s))
+		}
+		v.SetInt(x)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		x, err := strconv.Atoui64(s)
+		if err != nil {
+			return err
+		}
+		if v.OverflowUint(x) {
+			return os.NewError(fmt.Sprint("number cannot be stored in ", v.Type(), ": ", s))
+		}
+		v.SetUint(x)
+	case reflect.Float32, reflect.Float64:
+		x, err := strconv.Atof64(s)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(x)
+	case reflect.Slice:
+		var elems []string
+		if s != "" {
+			elems = strings.Split(s, ",")
+		}
+		sv := reflect.MakeSlice(v.Type(), len(elems), len(elems))
+		for i, elem := range elems {
+			err := bindString(sv.Index(i), strings.TrimSpace(elem))
+			if err != nil {
+				return err
+			}
+		}
+		v.Set(sv)
+	default:
+		return os.NewError(fmt.Sprint("default value not supported for type: ", v.Type()))
+	}
+	return nil
+}
+
+// appendPath returns a new path with seg appended, never sharing
+// the backing array of path.
+func appendPath(path []segment, seg segment) []segment {
+	return joinPath(path, []segment{seg})
+}
+
+// typeName returns the JSON type name of a property value.
+func typeName(prop interface{}) string {
+	switch prop.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case string, envValue:
+		return "string"
+	case Number:
+		return "number"
+	case map[string]interface{}:
+		return "map"
+	case []interface{}:
+		return "array"
+	}
+	return fmt.Sprint(reflect.TypeOf(prop))
+}
@@ -0,0 +1,147 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config
+
+import (
+	"strings"
+	"strconv"
+)
+
+// Coercion specifies how property values that are not of the type
+// requested are converted by the getters, such as a string "8080" for
+// Int64. Each field converts a property value, which is a string,
+// Number, bool, nil, map or array, and returns false if the value
+// cannot be converted. A team may replace any of the rules with its
+// own converter, which may call the default to handle other values.
+type Coercion struct {
+	Bool   func(v interface{}) (bool, bool)   // CoerceBool if nil
+	Number func(v interface{}) (Number, bool) // CoerceNumber if nil
+	String func(v interface{}) (string, bool) // CoerceString if nil
+}
+
+// SetCoercion enables conversion of property values that are not of
+// the type requested, or disables it if c is nil. Bool uses the Bool
+// rule, the number getters such as Int64 and Float64 use the Number
+// rule, and String uses the String rule, as do the array and map
+// getters for their elements. Values from the environment are passed
+// to the rules as strings. Properties retrieved from p after it is
+// called share the coercion.
+func (p *Properties) SetCoercion(c *Coercion) {
+	p.coerce = c
+}
+
+// CoerceBool is the default rule for Bool. It accepts the strings
+// "true", "yes", "on", "t", "y" and "1" as true and "false", "no",
+// "off", "f", "n" and "0" as false, in any case and ignoring spaces,
+// and the numbers 1 and 0.
+func CoerceBool(v interface{}) (bool, bool) {
+	switch t := v.(type) {
+	case bool:
+		return t, true
+	case string:
+		switch strings.ToLower(strings.TrimSpace(t)) {
+		case "true", "yes", "on", "t", "y", "1":
+			return true, true
+		case "false", "no", "off", "f", "n", "0":
+			return false, true
+		}
+	case Number:
+		if i, err := t.Int64(); err == nil && (i == 0 || i == 1) {
+			return i == 1, true
+		}
+	}
+	return false, false
+}
+
+// CoerceNumber is the default rule for the number getters. It accepts
+// strings containing a number in any of the forms of a config file,
+// such as "42", " 1.5e3 " or "0x1F", ignoring spaces.
+func CoerceNumber(v interface{}) (Number, bool) {
+	switch t := v.(type) {
+	case Number:
+		return t, true
+	case string:
+		return number(strings.TrimSpace(t))
+	}
+	return "", false
+}
+
+// CoerceString is the default rule for String. It formats numbers as
+// they appear in the config file and booleans as "true" or "false".
+func CoerceString(v interface{}) (string, bool) {
+	switch t := v.(type) {
+	case string:
+		return t, true
+	case Number:
+		return string(t), true
+	case bool:
+		return strconv.Btoa(t), true
+	}
+	return "", false
+}
+
+// toBool converts a property value to a bool, parsing a value from
+// the environment, or applying the coercion if there is one.
+func (p *Properties) toBool(prop interface{}) (bool, bool) {
+	switch v := prop.(type) {
+	case bool:
+		return v, true
+	case envValue:
+		if p.coerce == nil {
+			b, err := strconv.Atob(string(v))
+			return b, err == nil
+		}
+		prop = string(v)
+	}
+	if p.coerce == nil {
+		return false, false
+	}
+	if p.coerce.Bool != nil {
+		return p.coerce.Bool(prop)
+	}
+	return CoerceBool(prop)
+}
+
+// toNumber converts a property value to a Number, validating a value
+// from the environment, or applying the coercion if there is one.
+func (p *Properties) toNumber(prop interface{}) (Number, bool) {
+	switch v := prop.(type) {
+	case Number:
+		return v, true
+	case envValue:
+		if p.coerce == nil {
+			s := strings.TrimSpace(string(v))
+			if _, err := strconv.Atof64(s); err != nil {
+				return "", false
+			}
+			return Number(s), true
+		}
+		prop = string(v)
+	}
+	if p.coerce == nil {
+		return "", false
+	}
+	if p.coerce.Number != nil {
+		return p.coerce.Number(prop)
+	}
+	return CoerceNumber(prop)
+}
+
+// toString converts a property value to a string, applying
+// the coercion if there is one.
+func (p *Properties) toString(prop interface{}) (string, bool) {
+	switch v := prop.(type) {
+	case string:
+		return v, true
+	case envValue:
+		return string(v), true
+	}
+	if p.coerce == nil {
+		return "", false
+	}
+	if p.coerce.String != nil {
+		return p.coerce.String(prop)
+	}
+	return CoerceString(prop)
+}
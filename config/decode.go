@@ -0,0 +1,296 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config
+
+import (
+	"os"
+	"bytes"
+	"strconv"
+)
+
+// decodeJSON decodes a single JSON value, followed only by whitespace,
+// into the representation used by the property tree. Unlike the json
+// package, numbers are decoded as Number rather than float64.
+func decodeJSON(data []byte) (interface{}, os.Error) {
+	d := &jsonDecoder{data: data}
+	d.space()
+	v, err := d.value()
+	if err != nil {
+		return nil, err
+	}
+	d.space()
+	if d.pos < len(d.data) {
+		return nil, d.errorf("invalid character '%c' after top-level value.", d.data[d.pos])
+	}
+	return v, nil
+}
+
+type jsonDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *jsonDecoder) errorf(msg string, args ...interface{}) *ParseError {
+	return syntaxErrorAt("json", d.data, d.pos, msg, args...)
+}
+
+// unexpected returns an error for the character at the current
+// position, with a hint for some common mistakes.
+func (d *jsonDecoder) unexpected(context string) *ParseError {
+	if d.pos >= len(d.data) {
+		e := d.errorf("unexpected end of input %s.", context)
+		e.Hint = "a closing brace or bracket may be missing."
+		return e
+	}
+	c := d.data[d.pos]
+	e := d.errorf("invalid character '%c' %s.", c, context)
+	switch {
+	case c == '\'':
+		e.Hint = "strings must be enclosed in double quotes."
+	case c == '/' || c == '#':
+		e.Hint = "comments are not allowed in JSON."
+	case c == 'T' || c == 'F' || c == 'N':
+		e.Hint = "the literals true, false and null must be lower case."
+	}
+	return e
+}
+
+func (d *jsonDecoder) space() {
+	for d.pos < len(d.data) {
+		switch d.data[d.pos] {
+		case ' ', '\t', '\r', '\n':
+			d.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (d *jsonDecoder) peek() byte {
+	if d.pos >= len(d.data) {
+		return 0
+	}
+	return d.data[d.pos]
+}
+
+func (d *jsonDecoder) literal(s string, v interface{}) (interface{}, os.Error) {
+	if !bytes.HasPrefix(d.data[d.pos:], []byte(s)) {
+		return nil, d.unexpected("looking for beginning of value")
+	}
+	d.pos += len(s)
+	return v, nil
+}
+
+func (d *jsonDecoder) value() (interface{}, os.Error) {
+	switch c := d.peek(); {
+	case c == '{':
+		return d.object()
+	case c == '[':
+		return d.array()
+	case c == '"':
+		return d.str()
+	case c == 't':
+		return d.literal("true", true)
+	case c == 'f':
+		return d.literal("false", false)
+	case c == 'n':
+		return d.literal("null", nil)
+	case c == '-' || ('0' <= c && c <= '9'):
+		return d.number()
+	}
+	return nil, d.unexpected("looking for beginning of value")
+}
+
+func (d *jsonDecoder) object() (interface{}, os.Error) {
+	d.pos++
+	m := make(map[string]interface{})
+	d.space()
+	if d.peek() == '}' {
+		d.pos++
+		return m, nil
+	}
+	for {
+		d.space()
+		if c := d.peek(); c != '"' {
+			e := d.unexpected("looking for beginning of object key string")
+			switch {
+			case c == '}':
+				e.Hint = "trailing commas are not allowed in JSON."
+			case c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z'):
+				e.Hint = "object keys must be enclosed in double quotes."
+			}
+			return nil, e
+		}
+		key, err := d.str()
+		if err != nil {
+			return nil, err
+		}
+		d.space()
+		if d.peek() != ':' {
+			e := d.unexpected("after object key")
+			if d.peek() == '=' {
+				e.Hint = "object keys must be followed by ':'."
+			}
+			return nil, e
+		}
+		d.pos++
+		d.space()
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+		d.space()
+		switch d.peek() {
+		case ',':
+			d.pos++
+		case '}':
+			d.pos++
+			return m, nil
+		default:
+			e := d.unexpected("after object key:value pair")
+			if d.peek() == '"' {
+				e.Hint = "a comma may be missing after the previous value."
+			}
+			return nil, e
+		}
+	}
+	panic("unreachable")
+}
+
+func (d *jsonDecoder) array() (interface{}, os.Error) {
+	d.pos++
+	a := []interface{}{}
+	d.space()
+	if d.peek() == ']' {
+		d.pos++
+		return a, nil
+	}
+	for {
+		d.space()
+		if d.peek() == ']' && len(a) > 0 {
+			e := d.unexpected("looking for beginning of value")
+			e.Hint = "trailing commas are not allowed in JSON."
+			return nil, e
+		}
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, v)
+		d.space()
+		switch d.peek() {
+		case ',':
+			d.pos++
+		case ']':
+			d.pos++
+			return a, nil
+		default:
+			e := d.unexpected("after array element")
+			if c := d.peek(); c == '"' || c == '{' || c == '[' || ('0' <= c && c <= '9') {
+				e.Hint = "a comma may be missing after the previous value."
+			}
+			return nil, e
+		}
+	}
+	panic("unreachable")
+}
+
+func (d *jsonDecoder) number() (interface{}, os.Error) {
+	start := d.pos
+	for d.pos < len(d.data) {
+		c := d.data[d.pos]
+		if !('0' <= c && c <= '9') && c != '-' && c != '+' && c != '.' && c != 'e' && c != 'E' {
+			break
+		}
+		d.pos++
+	}
+	s := string(d.data[start:d.pos])
+	if !isJSONNumber(s) {
+		d.pos = start
+		return nil, d.errorf("invalid number literal '%s'.", s)
+	}
+	return Number(s), nil
+}
+
+func (d *jsonDecoder) str() (string, os.Error) {
+	d.pos++
+	var buf bytes.Buffer
+	for d.pos < len(d.data) {
+		c := d.data[d.pos]
+		switch {
+		case c == '"':
+			d.pos++
+			return buf.String(), nil
+		case c < 0x20:
+			return "", d.unexpected("in string literal")
+		case c == '\\':
+			d.pos++
+			err := d.escape(&buf)
+			if err != nil {
+				return "", err
+			}
+		default:
+			buf.WriteByte(c)
+			d.pos++
+		}
+	}
+	return "", d.errorf("unexpected end of input in string literal.")
+}
+
+func (d *jsonDecoder) escape(buf *bytes.Buffer) os.Error {
+	c := d.peek()
+	d.pos++
+	switch c {
+	case '"', '\\', '/':
+		buf.WriteByte(c)
+	case 'b':
+		buf.WriteByte('\b')
+	case 'f':
+		buf.WriteByte('\f')
+	case 'n':
+		buf.WriteByte('\n')
+	case 'r':
+		buf.WriteByte('\r')
+	case 't':
+		buf.WriteByte('\t')
+	case 'u':
+		r, ok := d.hex4()
+		if !ok {
+			return d.errorf("invalid unicode escape in string literal.")
+		}
+		// Combine a UTF-16 surrogate pair into a single character.
+		if 0xD800 <= r && r < 0xDC00 {
+			save := d.pos
+			r2 := -1
+			if bytes.HasPrefix(d.data[d.pos:], []byte(`\u`)) {
+				d.pos += 2
+				r2, ok = d.hex4()
+			}
+			if ok && 0xDC00 <= r2 && r2 < 0xE000 {
+				r = (r-0xD800)<<10 | (r2 - 0xDC00) + 0x10000
+			} else {
+				d.pos = save
+				r = 0xFFFD
+			}
+		}
+		buf.WriteRune(r)
+	default:
+		d.pos--
+		return d.unexpected("in string escape code")
+	}
+	return nil
+}
+
+func (d *jsonDecoder) hex4() (int, bool) {
+	if d.pos+4 > len(d.data) {
+		return 0, false
+	}
+	u, err := strconv.Btoui64(string(d.data[d.pos:d.pos+4]), 16)
+	if err != nil {
+		return 0, false
+	}
+	d.pos += 4
+	return int(u), true
+}
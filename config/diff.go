@@ -0,0 +1,283 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config
+
+import (
+	"os"
+	"io"
+	"sort"
+	"bytes"
+	"strconv"
+)
+
+// ChangeKind describes how a property value changed.
+type ChangeKind int
+
+const (
+	Added ChangeKind = iota
+	Removed
+	Modified
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Modified:
+		return "modified"
+	}
+	return "ChangeKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Change describes a single property value that differs between
+// two property trees. Old is nil for added values and New is nil
+// for removed values.
+type Change struct {
+	Name string // the full property name
+	Kind ChangeKind
+	Old  interface{}
+	New  interface{}
+}
+
+// DiffOptions specifies how Diff compares property values.
+type DiffOptions struct {
+	IgnoreOrder  bool // compare arrays regardless of the order of their elements
+	NumericEqual bool // compare numbers by value, so that 1 and 1.0 are equal
+}
+
+// Diff returns the changes from a to b, in the order of the property
+// tree with map keys sorted. Values are compared as stored, without
+// the environment overlay or interpolation, down to the leaves, which
+// are the values that are not maps or arrays, and empty maps and
+// arrays. Each change names a leaf, except that a value replaced by
+// a different kind of value is a single change. When order
+// is ignored, array elements of a without an equal element in b are
+// removed, and elements of b without an equal element in a are added.
+// The options may be nil.
+func Diff(a, b *Properties, opts *DiffOptions) []*Change {
+	defer rlockPair(a, b)()
+	return diff(a.root, b.root, nil, nil, opts)
+}
+
+// WriteDiff writes changes as text in the style of a unified diff,
+// with a line for each old value prefixed by "- " and a line for
+// each new value prefixed by "+ ". Each line holds the property name,
+// a colon and the value encoded as JSON on a single line:
+//
+//	- server.port: 8080
+//	+ server.port: 9090
+//	+ server.aliases[2]: "api"
+func WriteDiff(w io.Writer, changes []*Change) os.Error {
+	var buf bytes.Buffer
+	line := func(prefix, name string, v interface{}) os.Error {
+		buf.WriteString(prefix + name + ": ")
+		err := encodeCompact(&buf, v)
+		buf.WriteByte('\n')
+		return err
+	}
+	for _, c := range changes {
+		var err os.Error
+		if c.Kind != Added {
+			err = line("- ", c.Name, c.Old)
+		}
+		if err == nil && c.Kind != Removed {
+			err = line("+ ", c.Name, c.New)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// diff appends the changes from a to b below path to changes. Values
+// are compared down to the leaves visited by walk, except that a
+// container replaced by a different kind of value is a single change.
+func diff(a, b interface{}, path []segment, changes []*Change, opts *DiffOptions) []*Change {
+	switch x := a.(type) {
+	case map[string]interface{}:
+		if y, ok := b.(map[string]interface{}); ok && len(x) > 0 && len(y) > 0 {
+			keys := make([]string, 0, len(x)+len(y))
+			for key := range x {
+				keys = append(keys, key)
+			}
+			for key := range y {
+				if _, ok := x[key]; !ok {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				kpath := appendPath(path, segment{key, false})
+				xv, xok := x[key]
+				yv, yok := y[key]
+				switch {
+				case !yok:
+					changes = leaves(xv, kpath, Removed, changes)
+				case !xok:
+					changes = leaves(yv, kpath, Added, changes)
+				default:
+					changes = diff(xv, yv, kpath, changes, opts)
+				}
+			}
+			return changes
+		}
+	case []interface{}:
+		if y, ok := b.([]interface{}); ok && len(x) > 0 && len(y) > 0 {
+			if opts != nil && opts.IgnoreOrder {
+				return diffUnordered(x, y, path, changes, opts)
+			}
+			for i := 0; i < len(x) || i < len(y); i++ {
+				ipath := appendPath(path, segment{strconv.Itoa(i), true})
+				switch {
+				case i >= len(y):
+					changes = leaves(x[i], ipath, Removed, changes)
+				case i >= len(x):
+					changes = leaves(y[i], ipath, Added, changes)
+				default:
+					changes = diff(x[i], y[i], ipath, changes, opts)
+				}
+			}
+			return changes
+		}
+	}
+	if !opts.same(a, b) {
+		changes = append(changes, &Change{formatPath(path), Modified, a, b})
+	}
+	return changes
+}
+
+// diffUnordered appends the changes from x to y below path to changes,
+// matching each element of x with an equal element of y.
+func diffUnordered(x, y []interface{}, path []segment, changes []*Change, opts *DiffOptions) []*Change {
+	matched := matchElements(x, y, opts)
+	used := make([]bool, len(y))
+	for i, j := range matched {
+		if j < 0 {
+			changes = leaves(x[i], appendPath(path, segment{strconv.Itoa(i), true}), Removed, changes)
+		} else {
+			used[j] = true
+		}
+	}
+	for j := range y {
+		if !used[j] {
+			changes = leaves(y[j], appendPath(path, segment{strconv.Itoa(j), true}), Added, changes)
+		}
+	}
+	return changes
+}
+
+// matchElements returns the index of an equal element of y for each
+// element of x, or -1 if there is none, using each element of y once.
+func matchElements(x, y []interface{}, opts *DiffOptions) []int {
+	matched := make([]int, len(x))
+	used := make([]bool, len(y))
+	for i := range x {
+		matched[i] = -1
+		for j := range y {
+			if !used[j] && opts.same(x[i], y[j]) {
+				matched[i] = j
+				used[j] = true
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// same reports whether two property values are deeply equal
+// under the options, which may be nil.
+func (opts *DiffOptions) same(a, b interface{}) bool {
+	if opts == nil {
+		return equal(a, b)
+	}
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for key, xv := range x {
+			yv, ok := y[key]
+			if !ok || !opts.same(xv, yv) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		if opts.IgnoreOrder {
+			for _, j := range matchElements(x, y, opts) {
+				if j < 0 {
+					return false
+				}
+			}
+			return true
+		}
+		for i := range x {
+			if !opts.same(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case Number:
+		if opts.NumericEqual {
+			return sameValue(a, b)
+		}
+	}
+	return equal(a, b)
+}
+
+// leaves appends a change of the specified kind for every leaf below v.
+func leaves(v interface{}, path []segment, kind ChangeKind, changes []*Change) []*Change {
+	walk(v, path, func(lpath []segment, lv interface{}) os.Error {
+		c := &Change{Name: formatPath(lpath), Kind: kind}
+		if kind == Removed {
+			c.Old = lv
+		} else {
+			c.New = lv
+		}
+		changes = append(changes, c)
+		return nil
+	})
+	return changes
+}
+
+// equal reports whether two property values are deeply equal.
+func equal(a, b interface{}) bool {
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for key, xv := range x {
+			yv, ok := y[key]
+			if !ok || !equal(xv, yv) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case nil, bool, string, Number:
+		return a == b
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// EnvOverlay specifies environment variables that override property
+// values. The variable for a property is the Prefix followed by the
+// result of Transform applied to the property name segments, for
+// example "server.port" with prefix "APP_" maps to APP_SERVER_PORT.
+// Variables are strings and are parsed into the type requested by
+// Bool, Int64, Float64 and String.
+type EnvOverlay struct {
+	Prefix    string
+	Transform func(name []string) string // EnvName if nil
+}
+
+// envValue is a property value taken from the environment.
+type envValue string
+
+// EnvName is the default transform from property name segments to an
+// environment variable name. Segments are upper cased and joined with
+// underscores, other characters that are not letters or digits are
+// replaced with underscores.
+func EnvName(name []string) string {
+	s := strings.ToUpper(strings.Join(name, "_"))
+	return strings.Map(func(c int) int {
+		if ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') {
+			return c
+		}
+		return '_'
+	}, s)
+}
+
+// SetEnvOverlay enables lookups in the environment before the property
+// values, or disables them if the overlay is nil. Properties retrieved
+// from p after it is called share the overlay.
+func (p *Properties) SetEnvOverlay(env *EnvOverlay) {
+	p.env = env
+}
+
+// EnvOverrides returns the names of the properties whose values are
+// overridden by environment variables, in sorted order. These are the
+// properties in p with a variable set, and the properties not in p
+// named by a variable with the prefix, read as the lower case segments
+// between its underscores, that the transform maps back to the variable.
+func (p *Properties) EnvOverrides() []string {
+	var names []string
+	if p.env == nil {
+		return names
+	}
+	p.rlock()
+	defer p.runlock()
+	seen := make(map[string]bool)
+	walk(p.root, p.path, func(path []segment, v interface{}) os.Error {
+		if _, ok := p.envLookup(path); ok {
+			seen[p.env.variable(path)] = true
+			names = append(names, formatPath(path))
+		}
+		return nil
+	})
+	for _, kv := range os.Environ() {
+		variable := kv
+		if i := strings.Index(kv, "="); i >= 0 {
+			variable = kv[:i]
+		}
+		if seen[variable] {
+			continue
+		}
+		path := p.env.path(variable)
+		if len(path) <= len(p.path) {
+			continue
+		}
+		for i, seg := range p.path {
+			if path[i].key != seg.key {
+				path = nil
+				break
+			}
+		}
+		if _, ok := p.envLookup(path); ok {
+			seen[variable] = true
+			names = append(names, formatPath(path))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// envLookup returns the value from the environment overlay for the
+// property at path within the top level properties, unless the value
+// was set by a command line flag. It is called with the lock held.
+func (p *Properties) envLookup(path []segment) (envValue, bool) {
+	if p.env == nil || p.topLevel().flagged(path) {
+		return "", false
+	}
+	return p.env.lookup(path)
+}
+
+// variable returns the environment variable name for a property path.
+func (env *EnvOverlay) variable(path []segment) string {
+	name := make([]string, len(path))
+	for i, seg := range path {
+		name[i] = seg.key
+	}
+	transform := env.Transform
+	if transform == nil {
+		transform = EnvName
+	}
+	return env.Prefix + transform(name)
+}
+
+// path returns the property path of a variable, read as the lower case
+// segments between the underscores following the prefix, or nil if the
+// transform does not map the path back to the variable.
+func (env *EnvOverlay) path(variable string) []segment {
+	if !strings.HasPrefix(variable, env.Prefix) || len(variable) == len(env.Prefix) {
+		return nil
+	}
+	keys := strings.Split(strings.ToLower(variable[len(env.Prefix):]), "_")
+	path := make([]segment, len(keys))
+	for i, key := range keys {
+		path[i] = segment{key, false}
+	}
+	if env.variable(path) != variable {
+		return nil
+	}
+	return path
+}
+
+func (env *EnvOverlay) lookup(path []segment) (envValue, bool) {
+	if len(path) == 0 {
+		return "", false
+	}
+	v, err := os.Getenverror(env.variable(path))
+	if err != nil {
+		return "", false
+	}
+	return envValue(v), true
+}
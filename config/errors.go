@@ -0,0 +1,193 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config
+
+import (
+	"os"
+	"fmt"
+	"bytes"
+	"strings"
+	"utf8"
+)
+
+// ParseError describes a syntax error in config data, with the position
+// of the error and the text of the line containing it.
+type ParseError struct {
+	File   string // the config file name, empty if not read from a file
+	Format string // the file format, such as "json" or "yaml"
+	Line   int
+	Column int    // the column in characters, zero if not known
+	Text   string // the line containing the error
+	Msg    string
+	Hint   string // a description of a likely mistake, may be empty
+}
+
+// String returns the position and message of the error, followed
+// by the excerpt and the hint on separate lines.
+func (e *ParseError) String() string {
+	name := e.Format
+	if e.File != "" {
+		name = e.File
+	}
+	s := fmt.Sprintf("%s: line %d", name, e.Line)
+	if e.Column > 0 {
+		s += fmt.Sprintf(", column %d", e.Column)
+	}
+	s += ": " + e.Msg
+	if excerpt := e.Excerpt(); excerpt != "" {
+		s += "\n" + excerpt
+	}
+	if e.Hint != "" {
+		s += "\n\t" + e.Hint
+	}
+	return s
+}
+
+// Excerpt returns the line containing the error with a caret on the
+// line below marking the column, or the start of the text if the
+// column is not known.
+func (e *ParseError) Excerpt() string {
+	if strings.TrimSpace(e.Text) == "" {
+		return ""
+	}
+	col := e.Column
+	if col < 1 {
+		col = 1 + utf8.RuneCountInString(e.Text) - utf8.RuneCountInString(strings.TrimLeft(e.Text, " \t"))
+	}
+	var caret bytes.Buffer
+	i := 1
+	for _, c := range e.Text {
+		if i >= col {
+			break
+		}
+		if c == '\t' {
+			caret.WriteByte('\t')
+		} else {
+			caret.WriteByte(' ')
+		}
+		i++
+	}
+	caret.WriteByte('^')
+	return "\t" + e.Text + "\n\t" + caret.String()
+}
+
+// syntaxError returns an error for a syntax error in a config file.
+func syntaxError(format string, line int, msg string, args ...interface{}) *ParseError {
+	return &ParseError{Format: format, Line: line, Msg: fmt.Sprintf(msg, args...)}
+}
+
+// syntaxErrorAt returns an error for a syntax error at
+// the byte offset pos in the config data.
+func syntaxErrorAt(format string, data []byte, pos int, msg string, args ...interface{}) *ParseError {
+	if pos > len(data) {
+		pos = len(data)
+	}
+	start := bytes.LastIndex(data[:pos], []byte{'\n'}) + 1
+	e := syntaxError(format, 1+bytes.Count(data[:start], []byte{'\n'}), msg, args...)
+	e.Column = 1 + utf8.RuneCount(data[start:pos])
+	return e
+}
+
+// withText fills in the text of the line containing a parse error.
+func withText(err os.Error, data []byte) os.Error {
+	e, ok := err.(*ParseError)
+	if !ok || e.Text != "" || e.Line < 1 {
+		return err
+	}
+	lines := bytes.Split(data, []byte{'\n'})
+	if e.Line <= len(lines) {
+		e.Text = strings.TrimRight(string(lines[e.Line-1]), "\r")
+	}
+	return e
+}
+
+// withFile records the name of the file containing a parse error.
+func withFile(err os.Error, fname string) os.Error {
+	if e, ok := err.(*ParseError); ok && e.File == "" {
+		e.File = fname
+	}
+	return err
+}
+
+// NameError is returned when a property name cannot be parsed.
+type NameError struct {
+	Name   string
+	Offset int // the byte offset of the error in Name
+	Msg    string
+}
+
+func (e *NameError) String() string {
+	return fmt.Sprintf("property name is not valid: %s, %s at offset %d", e.Name, e.Msg, e.Offset)
+}
+
+// NotFoundError is returned when a property name refers to
+// a map key that does not exist.
+type NotFoundError struct {
+	Name    string // the full property name
+	Segment string // the name of the missing property, Name or a prefix of it
+}
+
+func (e *NotFoundError) String() string {
+	if e.Segment == e.Name {
+		return "property not found: " + e.Name
+	}
+	return "property not found: " + e.Segment + ", cannot get property: " + e.Name
+}
+
+// IndexOutOfRangeError is returned when a property name refers to
+// an array element that does not exist.
+type IndexOutOfRangeError struct {
+	Name    string // the full property name
+	Segment string // the name of the missing element, Name or a prefix of it
+	Index   int64
+	Len     int // the length of the array
+}
+
+func (e *IndexOutOfRangeError) String() string {
+	s := fmt.Sprintf("array index out of range: %s, length %d", e.Segment, e.Len)
+	if e.Segment != e.Name {
+		s += ", cannot get property: " + e.Name
+	}
+	return s
+}
+
+// TypeMismatchError is returned when a property value is not of the
+// type requested, or when a property name continues past a value that
+// is not a map or array.
+type TypeMismatchError struct {
+	Name     string // the full property name
+	Segment  string // the name of the value with the wrong type, Name or a prefix of it
+	Expected string // the type requested, such as "bool", "map" or "array"
+	Actual   string // the type of the value, such as "string" or "number"
+}
+
+func (e *TypeMismatchError) String() string {
+	s := fmt.Sprintf("property is not of type '%s': %s is of type '%s'", e.Expected, e.Segment, e.Actual)
+	if e.Segment != e.Name {
+		s += ", cannot get property: " + e.Name
+	}
+	return s
+}
+
+// ValueError is returned when a property value of the type requested
+// cannot be converted, such as a number that is out of range or a
+// duration that is not valid.
+type ValueError struct {
+	Name string // the full property name
+	Err  os.Error
+}
+
+func (e *ValueError) String() string {
+	return "property value is not valid: " + e.Name + ", " + e.Err.String()
+}
+
+// IsNotFound reports whether err reports that a property does not exist,
+// rather than that it exists but has a value that cannot be used.
+func IsNotFound(err os.Error) bool {
+	switch err.(type) {
+	case *NotFoundError, *IndexOutOfRangeError:
+		return true
+	}
+	return false
+}
@@ -5,30 +5,129 @@ package config
 
 import (
 	"os"
+	"sync"
+	"io/ioutil"
+	"path/filepath"
 )
 
 type ConfigFile struct {
 	*Properties
-	fname string
+	fname  string
+	schema *Schema // checked by Reload before replacing the properties
 }
 
 // ReadConfigFile reads the specified file and reads the config properties.
-func ReadConfigFile(fname string) (c *ConfigFile, err os.Error) {
-	var f *os.File
-	f, err = os.Open(fname)
+// The format of the file is chosen by its extension, ".json", ".yaml",
+// ".yml", ".toml", ".ini", ".properties" or any extension given to
+// RegisterFormat.
+// Files with other extensions are read as JSON.
+// A map in the file may include other config files with the key
+// "$include", whose value is a file name or an array of file names
+// relative to the including file. File names may be glob patterns,
+// such as "conf.d/*.json". The values of the included files are merged
+// in order, then the other keys of the map are merged over them. The
+// name of the file that supplied a value is reported by Origin. Save
+// writes the merged values, without the include directives.
+// If a schema is given the properties are validated against it,
+// and a *SchemaError is returned if they do not match.
+func ReadConfigFile(fname string, schema ...*Schema) (c *ConfigFile, err os.Error) {
+	var p *Properties
+	p, err = readConfigFile(fname)
 	if err != nil {
 		return
 	}
 
-	var p *Properties
-	p, err = ReadProperties(f)
-	if err != nil {
-		return
+	p.mu = new(sync.RWMutex)
+	c = &ConfigFile{Properties: p, fname: fname}
+	if len(schema) > 0 && schema[0] != nil {
+		c.schema = schema[0]
+		err = p.Validate(c.schema)
+		if err != nil {
+			return nil, err
+		}
 	}
+	return c, nil
+}
 
-	return &ConfigFile{p, fname}, nil
+// FileName returns the name of the file used by Save.
+func (c *ConfigFile) FileName() string {
+	c.rlock()
+	defer c.runlock()
+	return c.fname
 }
 
+// SetFileName changes the name of the file used by Save.
 func (c *ConfigFile) SetFileName(fname string) {
+	c.wlock()
+	defer c.wunlock()
 	c.fname = fname
 }
+
+// Save writes the config properties to the file they were read from,
+// or to the file name most recently given to SetFileName or SaveAs.
+// It fails for file formats that can be read but not written.
+func (c *ConfigFile) Save() os.Error {
+	fname := c.FileName()
+	if fname == "" {
+		return os.NewError("config file name is not set, cannot save.")
+	}
+	return writeFile(fname, c.Properties)
+}
+
+// SaveAs writes the config properties to the specified file
+// and changes the file name used by subsequent calls to Save.
+func (c *ConfigFile) SaveAs(fname string) os.Error {
+	err := writeFile(fname, c.Properties)
+	if err != nil {
+		return err
+	}
+	c.SetFileName(fname)
+	return nil
+}
+
+// writeFile writes properties to a temporary file in the same directory
+// and then renames it over fname, so that fname is always either the
+// old or the new complete contents. The permissions of an existing
+// file are preserved. The format is chosen by the file extension.
+func writeFile(fname string, p *Properties) (err os.Error) {
+	format := formatOf(fname)
+	if format.Write == nil {
+		return os.NewError("config file format cannot be written: " + fname)
+	}
+
+	dir, base := filepath.Split(fname)
+	if dir == "" {
+		dir = "."
+	}
+
+	var f *os.File
+	f, err = ioutil.TempFile(dir, "."+base+".")
+	if err != nil {
+		return
+	}
+	tname := f.Name()
+	defer func() {
+		if err != nil {
+			os.Remove(tname)
+		}
+	}()
+
+	err = format.Write(f, p)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return
+	}
+
+	if fi, serr := os.Stat(fname); serr == nil {
+		err = os.Chmod(tname, fi.Permission())
+		if err != nil {
+			return
+		}
+	}
+	return os.Rename(tname, fname)
+}
@@ -0,0 +1,88 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config_test
+
+import (
+	"os"
+	"fmt"
+	"config"
+	"strings"
+	"testing"
+	"io/ioutil"
+	"path/filepath"
+)
+
+var TestIncludeFiles = map[string]string{
+	"main.json": `{
+	"$include":[ "base.yaml", "conf.d/*.json" ],
+	"name":"main",
+	"server":{ "port":9090 }
+}`,
+	"base.yaml": `name: base
+server:
+  host: localhost
+  port: 8080
+`,
+	"conf.d/10-db.json":  `{ "db":{ "url":"postgres://db" } }`,
+	"conf.d/20-log.json": `{ "log":{ "level":"debug" }, "db":{ "pool":5 } }`,
+	"loop1.json":         `{ "$include":"loop2.json" }`,
+	"loop2.json":         `{ "$include":"loop1.json" }`,
+}
+
+func TestFileInclude(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "config_include")
+	if err != nil {
+		t.Fatal("Error creating temporary directory:", err)
+	}
+	defer os.RemoveAll(dir)
+	os.Mkdir(filepath.Join(dir, "conf.d"), 0755)
+	for name, data := range TestIncludeFiles {
+		err = ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644)
+		if err != nil {
+			t.Fatal("Error writing test config file:", name, err)
+		}
+	}
+
+	main := filepath.Join(dir, "main.json")
+	c, err := config.ReadConfigFile(main)
+	if err == nil {
+		t.Log("Success reading config file with includes.")
+	} else {
+		t.Fatal("Error reading config file with includes:", err)
+	}
+
+	values := []struct {
+		name, value, origin string
+	}{
+		{"name", "main", "main.json"},
+		{"server.host", "localhost", "base.yaml"},
+		{"server.port", "9090", "main.json"},
+		{"db.url", "postgres://db", "conf.d/10-db.json"},
+		{"db.pool", "5", "conf.d/20-log.json"},
+		{"log.level", "debug", "conf.d/20-log.json"},
+	}
+	for _, v := range values {
+		prop, _ := c.Property(v.name)
+		origin, _ := c.Origin(v.name)
+		if fmt.Sprint(prop) == v.value && origin == filepath.Join(dir, v.origin) {
+			t.Log("Value for '"+v.name+"' is", prop, "from", origin)
+		} else {
+			t.Error("Value for '"+v.name+"' is not", v.value, "from", v.origin, ":", prop, origin)
+		}
+	}
+
+	if _, err = c.Property("$include"); err != nil {
+		t.Log("Include directive is not a property value.")
+	} else {
+		t.Error("Include directive is a property value.")
+	}
+
+	_, err = config.ReadConfigFile(filepath.Join(dir, "loop1.json"))
+	if err != nil && strings.Index(err.String(), "cycle") >= 0 {
+		t.Log("Include cycle is reported:", err)
+	} else {
+		t.Error("Include cycle is not reported:", err)
+	}
+}
@@ -4,8 +4,11 @@
 package config_test
 
 import (
+	"os"
 	"config"
 	"testing"
+	"io/ioutil"
+	"path/filepath"
 )
 
 var TestFileName = "testdata/config.json"
@@ -33,3 +36,125 @@ func TestFile(t *testing.T) {
 		t.Error("Error getting string value from property 'host':", err)
 	}
 }
+
+var TestFormatFileNames = []string{
+	"testdata/config.json",
+	"testdata/config.yaml",
+	"testdata/config.toml",
+	"testdata/config.ini",
+}
+
+func TestFileFormats(t *testing.T) {
+
+	for _, fname := range TestFormatFileNames {
+		c, err := config.ReadConfigFile(fname)
+		if err != nil {
+			t.Error("Error reading test config file:", fname, err)
+			continue
+		}
+		host, _ := c.String("host")
+		port, _ := c.Int64("port")
+		user2, _ := c.String("users.user2")
+		if host == "localhost" && port == 8080 && user2 == "password2" {
+			t.Log("Properties read from file are the same:", fname)
+		} else {
+			t.Error("Properties read from file are not the same:", fname, host, port, user2)
+		}
+	}
+
+	c, err := config.ReadConfigFile("testdata/config.yaml")
+	if err != nil {
+		t.Fatal("Error reading test config file:", err)
+	}
+	err = c.Save()
+	if err != nil {
+		t.Log("Saving YAML config file returns error:", err)
+	} else {
+		t.Error("Saving YAML config file does not return error.")
+	}
+}
+
+var TestSaveConfigData = `{
+	"port": 8080,
+	"host": "localhost",
+	"users": [
+		"user1",
+		"user2"
+	]
+}`
+
+var TestSaveConfigOutput = `{
+	"host": "localhost",
+	"port": 9090,
+	"users": [
+		"user1",
+		"user2"
+	]
+}
+`
+
+func TestFileSave(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal("Error creating temp directory:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	fname := filepath.Join(dir, "config.json")
+	err = ioutil.WriteFile(fname, []byte(TestSaveConfigData), 0644)
+	if err != nil {
+		t.Fatal("Error writing test config file:", err)
+	}
+
+	c, err := config.ReadConfigFile(fname)
+	if err != nil {
+		t.Fatal("Error reading test config file:", err)
+	}
+
+	c.Set(9090, "port")
+	err = c.Save()
+	if err == nil {
+		t.Log("Success saving test config file")
+	} else {
+		t.Fatal("Error saving test config file:", err)
+	}
+
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		t.Fatal("Error reading saved config file:", err)
+	}
+	if string(b) == TestSaveConfigOutput {
+		t.Log("Saved config file has sorted keys and indentation.")
+	} else {
+		t.Error("Saved config file does not match expected output:\n" + string(b))
+	}
+
+	fname2 := filepath.Join(dir, "config2.json")
+	err = c.SaveAs(fname2)
+	if err != nil {
+		t.Fatal("Error saving test config file as:", fname2, err)
+	}
+	if c.FileName() == fname2 {
+		t.Log("File name after SaveAs is:", fname2)
+	} else {
+		t.Error("File name after SaveAs is not:", fname2)
+	}
+
+	c2, err := config.ReadConfigFile(fname2)
+	if err != nil {
+		t.Fatal("Error reading saved config file:", err)
+	}
+	if port, _ := c2.Int64("port"); port == 9090 {
+		t.Log("Int64 value for property 'port' is 9090.")
+	} else {
+		t.Error("Int64 value for property 'port' is not 9090.")
+	}
+
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) == 2 {
+		t.Log("No temporary files remain after save.")
+	} else {
+		t.Error("Temporary files remain after save:", len(files))
+	}
+}
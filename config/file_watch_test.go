@@ -0,0 +1,126 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config_test
+
+import (
+	"os"
+	"time"
+	"config"
+	"testing"
+	"io/ioutil"
+	"path/filepath"
+)
+
+var TestWatchConfigData = `{
+	"host":"localhost",
+	"port":8080,
+	"users":[ "user1" ]
+}`
+
+var TestWatchConfigData2 = `{
+	"host":"localhost",
+	"port":9090,
+	"users":[ "user1", "user2" ],
+	"debug":true
+}`
+
+func TestFileWatch(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal("Error creating temp directory:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	fname := filepath.Join(dir, "config.json")
+	err = ioutil.WriteFile(fname, []byte(TestWatchConfigData), 0644)
+	if err != nil {
+		t.Fatal("Error writing test config file:", err)
+	}
+
+	c, err := config.ReadConfigFile(fname)
+	if err != nil {
+		t.Fatal("Error reading test config file:", err)
+	}
+
+	w := c.Watch(5e6)
+	defer w.Stop()
+
+	err = replaceFile(fname, TestWatchConfigData2)
+	if err != nil {
+		t.Fatal("Error writing test config file:", err)
+	}
+
+	select {
+	case changes := <-w.Changes:
+		t.Log("Received changes after modifying config file.")
+		expected := []struct {
+			name string
+			kind config.ChangeKind
+		}{
+			{"debug", config.Added},
+			{"port", config.Modified},
+			{"users[1]", config.Added},
+		}
+		if len(changes) != len(expected) {
+			t.Fatal("Received wrong number of changes:", len(changes))
+		}
+		for i, e := range expected {
+			if changes[i].Name == e.name && changes[i].Kind == e.kind {
+				t.Log("Change for property '"+e.name+"' is", e.kind)
+			} else {
+				t.Error("Change for property '"+e.name+"' is not", e.kind, changes[i])
+			}
+		}
+	case err = <-w.Errors:
+		t.Fatal("Error reloading config file:", err)
+	case <-time.After(5e9):
+		t.Fatal("Timeout waiting for changes after modifying config file.")
+	}
+
+	if i, _ := c.Int64("port"); i == 9090 {
+		t.Log("Int64 value for property 'port' is 9090 after reload.")
+	} else {
+		t.Error("Int64 value for property 'port' is not 9090 after reload.")
+	}
+
+	err = replaceFile(fname, "{ \"port\": ")
+	if err != nil {
+		t.Fatal("Error writing test config file:", err)
+	}
+
+	select {
+	case <-w.Changes:
+		t.Fatal("Received changes after writing invalid config file.")
+	case err = <-w.Errors:
+		t.Log("Received error after writing invalid config file:", err)
+	case <-time.After(5e9):
+		t.Fatal("Timeout waiting for error after writing invalid config file.")
+	}
+
+	if i, _ := c.Int64("port"); i == 9090 {
+		t.Log("Int64 value for property 'port' is 9090 after failed reload.")
+	} else {
+		t.Error("Int64 value for property 'port' is not 9090 after failed reload.")
+	}
+
+	w.Stop()
+	w.Stop()
+	t.Log("Watcher is stopped twice.")
+
+	w = c.Watch(0)
+	w.Stop()
+	t.Log("Watcher with an interval of zero is started and stopped.")
+}
+
+// replaceFile writes data to a temporary file and renames it over fname,
+// so that the watcher never reads a partially written file.
+func replaceFile(fname, data string) os.Error {
+	tname := fname + ".tmp"
+	err := ioutil.WriteFile(tname, []byte(data), 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tname, fname)
+}
@@ -0,0 +1,224 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config
+
+import (
+	"os"
+	"flag"
+	"bytes"
+	"strings"
+	"strconv"
+)
+
+// Flags registers a flag with fs for each named property, with the same
+// name as the property, such as -server.port, and the current value of
+// the property as its default. A flag value is parsed as the type of the
+// property value in p, ignoring the environment overlay: a bool, a
+// number, a string, or JSON for a map or an array. A property whose
+// value is null takes a value inferred as by OverrideFlag. If fs is nil,
+// the flags are registered with the flag package's command line flags.
+// An error is returned if a property is not found.
+//
+// Values set by flags take precedence over the environment overlay, so
+// that reading a config file, setting an environment overlay and then
+// parsing the command line gives flags precedence over the environment
+// and the environment precedence over the file. The values are set
+// again when a ConfigFile is reloaded.
+func (p *Properties) Flags(fs *flag.FlagSet, names ...string) os.Error {
+	for _, name := range names {
+		path, err := parseName(name)
+		if err != nil {
+			return err
+		}
+		p.rlock()
+		prop, err := lookup(p.root, p.path, path)
+		p.runlock()
+		if err != nil {
+			return err
+		}
+		f := &propertyFlag{p, name, kindOf(prop)}
+		usage := "sets the property " + name
+		if fs == nil {
+			flag.Var(f, name, usage)
+		} else {
+			fs.Var(f, name, usage)
+		}
+	}
+	return nil
+}
+
+// OverrideFlag registers a repeatable flag with fs, such as -set, that
+// sets a property from a value of the form "name=value", for example
+// "-set server.port=9090 -set log.level=debug". The type of the value
+// is inferred: "true" and "false" are booleans, "null" is null, a JSON
+// number is a number, and a JSON map, array or quoted string is decoded.
+// Any other value is a string. If fs is nil, the flag is registered with
+// the flag package's command line flags. Like the values set by Flags,
+// the values set take precedence over the environment overlay.
+func (p *Properties) OverrideFlag(fs *flag.FlagSet, name string) {
+	f := &overrideFlag{p}
+	usage := "sets a property, as name=value"
+	if fs == nil {
+		flag.Var(f, name, usage)
+	} else {
+		fs.Var(f, name, usage)
+	}
+}
+
+// propertyFlag is the flag.Value of a flag registered by Flags.
+type propertyFlag struct {
+	p    *Properties
+	name string
+	kind Kind
+}
+
+func (f *propertyFlag) String() string {
+	if f.p == nil {
+		return ""
+	}
+	prop, err := f.p.Property(f.name)
+	if err != nil {
+		return ""
+	}
+	if s, ok := prop.(string); ok {
+		return s
+	}
+	var buf bytes.Buffer
+	encodeCompact(&buf, prop)
+	return buf.String()
+}
+
+func (f *propertyFlag) Set(s string) bool {
+	var v interface{}
+	switch f.kind {
+	case BoolKind:
+		b, err := strconv.Atob(s)
+		if err != nil {
+			return false
+		}
+		v = b
+	case NumberKind:
+		n, ok := number(strings.TrimSpace(s))
+		if !ok {
+			return false
+		}
+		v = n
+	case StringKind:
+		v = s
+	case MapKind, ArrayKind:
+		var err os.Error
+		v, err = decodeJSON([]byte(s))
+		if err != nil || kindOf(v) != f.kind {
+			return false
+		}
+	default:
+		v = inferValue(s)
+	}
+	return f.p.setFromFlag(f.name, v) == nil
+}
+
+// overrideFlag is the flag.Value of a flag registered by OverrideFlag.
+type overrideFlag struct {
+	p *Properties
+}
+
+func (f *overrideFlag) String() string {
+	return ""
+}
+
+func (f *overrideFlag) Set(s string) bool {
+	i := strings.Index(s, "=")
+	if i <= 0 {
+		return false
+	}
+	return f.p.setFromFlag(strings.TrimSpace(s[:i]), inferValue(s[i+1:])) == nil
+}
+
+// inferValue returns the property value of a flag value of a type
+// inferred from its syntax.
+func inferValue(s string) interface{} {
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+	if isJSONNumber(s) {
+		return Number(s)
+	}
+	if s != "" && strings.IndexAny(s[:1], "{[\"") == 0 {
+		if v, err := decodeJSON([]byte(s)); err == nil {
+			return v
+		}
+	}
+	return s
+}
+
+// setFromFlag stores a property value set by a flag, and records that
+// the value takes precedence over the environment overlay.
+func (p *Properties) setFromFlag(name string, v interface{}) os.Error {
+	path, err := parseName(name)
+	if err != nil {
+		return err
+	}
+	err = p.Set(v, name)
+	if err != nil {
+		return err
+	}
+	p.wlock()
+	defer p.wunlock()
+	top := p.topLevel()
+	full := joinPath(p.path, path)
+	key := originKey(full)
+	var flags []*flagValue
+	for _, f := range top.flags {
+		if f.key != key {
+			flags = append(flags, f)
+		}
+	}
+	top.flags = append(flags, &flagValue{full, key, v})
+	return nil
+}
+
+// flagValue is a property value set by a command line flag.
+type flagValue struct {
+	path  []segment // the full path of the property
+	key   string    // the origin key of the path
+	value interface{}
+}
+
+// flagged reports whether the value at path, or a value containing it,
+// was set by a flag. It is called on the top level properties.
+func (p *Properties) flagged(path []segment) bool {
+	for _, f := range p.flags {
+		if len(f.path) <= len(path) && originKey(path[:len(f.path)]) == f.key {
+			return true
+		}
+	}
+	return false
+}
+
+// setFlags stores the values set by flags in root, in the order they
+// were set, and records them in origins, so that the values are kept
+// when a config file is reloaded. A value whose parent is no longer a
+// map or array is skipped. It returns the updated root, and is called
+// on the top level properties with the lock held.
+func (p *Properties) setFlags(root interface{}, origins *originTree) interface{} {
+	for _, f := range p.flags {
+		v, err := normalize(f.value)
+		if err != nil {
+			continue
+		}
+		resolved, _ := resolveIndices(root, f.path)
+		r, err := set(root, f.path, 0, v)
+		if err != nil {
+			continue
+		}
+		root = r
+		origins.set(resolved, "")
+	}
+	return root
+}
@@ -0,0 +1,155 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config
+
+import (
+	"os"
+	"sort"
+	"strconv"
+)
+
+// Flatten returns the leaf property values visited by Walk, keyed by
+// their names, such as "server.aliases[0]". The names use PropNameDelim
+// and quote keys containing special characters, so FromFlat rebuilds
+// the same property tree from the map.
+func (p *Properties) Flatten() (map[string]interface{}, os.Error) {
+	flat := make(map[string]interface{})
+	err := p.Walk(func(name string, value interface{}) os.Error {
+		flat[name] = value
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return flat, nil
+}
+
+// FlattenStrings returns the leaf property values like Flatten, with the
+// values formatted as strings. Numbers are formatted as they appear in
+// the config file, booleans as "true" or "false", null as the empty
+// string, and empty maps and arrays as "{}" and "[]". FromFlatStrings
+// rebuilds the property tree from the map.
+func (p *Properties) FlattenStrings() (map[string]string, os.Error) {
+	flat := make(map[string]string)
+	err := p.Walk(func(name string, value interface{}) os.Error {
+		switch v := value.(type) {
+		case string:
+			flat[name] = v
+		case Number:
+			flat[name] = string(v)
+		case bool:
+			flat[name] = strconv.Btoa(v)
+		case nil:
+			flat[name] = ""
+		case map[string]interface{}:
+			flat[name] = "{}"
+		case []interface{}:
+			flat[name] = "[]"
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return flat, nil
+}
+
+// FromFlat creates Properties from a map of property names to values,
+// such as one returned by Flatten. The names are parsed as by the
+// getters, with a map created for each key and an array for each index.
+// Arrays are grown to hold the largest index, with null elements for
+// indices that are not given, as by Set. The values are converted as by
+// Set. An error is returned for a name that is not valid, or that
+// continues past a value given for another name.
+func FromFlat(flat map[string]interface{}) (*Properties, os.Error) {
+	entries := make(flatEntries, 0, len(flat))
+	for name := range flat {
+		path, err := parsePath(name)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, flatEntry{name, path})
+	}
+	sort.Sort(entries)
+
+	var root interface{}
+	for _, e := range entries {
+		v, err := normalize(flat[e.name])
+		if err != nil {
+			return nil, err
+		}
+		root, err = set(root, e.path, 0, v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if root == nil {
+		root = make(map[string]interface{})
+	}
+	return &Properties{root: root}, nil
+}
+
+// FromFlatStrings creates Properties from a map of property names to
+// strings, such as one returned by FlattenStrings, as by FromFlat with
+// the type of each value inferred from its string. The strings "true"
+// and "false" are booleans, JSON numbers are numbers, the empty string
+// is null, and "{}" and "[]" are an empty map and array. Other strings
+// are kept as strings, so a string that reads as another type, such
+// as "8080", is not rebuilt as a string.
+func FromFlatStrings(flat map[string]string) (*Properties, os.Error) {
+	values := make(map[string]interface{}, len(flat))
+	for name, s := range flat {
+		values[name] = flatValue(s)
+	}
+	return FromFlat(values)
+}
+
+// flatValue returns the value formatted by FlattenStrings as s.
+func flatValue(s string) interface{} {
+	switch s {
+	case "":
+		return nil
+	case "true":
+		return true
+	case "false":
+		return false
+	case "{}":
+		return make(map[string]interface{})
+	case "[]":
+		return []interface{}{}
+	}
+	if isJSONNumber(s) {
+		return Number(s)
+	}
+	return s
+}
+
+// flatEntry is a name given to FromFlat and its path.
+type flatEntry struct {
+	name string
+	path []segment
+}
+
+// flatEntries sorts entries by path, with indices in numeric order,
+// so that arrays are grown in order of their indices.
+type flatEntries []flatEntry
+
+func (e flatEntries) Len() int      { return len(e) }
+func (e flatEntries) Swap(i, j int) { e[i], e[j] = e[j], e[i] }
+
+func (e flatEntries) Less(i, j int) bool {
+	a, b := e[i].path, e[j].path
+	for k := 0; k < len(a) && k < len(b); k++ {
+		if a[k].key == b[k].key {
+			continue
+		}
+		x, xerr := strconv.Atoi64(a[k].key)
+		y, yerr := strconv.Atoi64(b[k].key)
+		if a[k].index && b[k].index && xerr == nil && yerr == nil {
+			return x < y
+		}
+		return a[k].key < b[k].key
+	}
+	return len(a) < len(b)
+}
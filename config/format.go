@@ -0,0 +1,117 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config
+
+import (
+	"os"
+	"io"
+	"math"
+	"strings"
+	"strconv"
+	"path/filepath"
+)
+
+// Format reads and writes config properties in a file format.
+type Format struct {
+	Read  func(r io.Reader) (*Properties, os.Error)
+	Write func(w io.Writer, p *Properties) os.Error // nil if not supported
+}
+
+var formats = map[string]*Format{
+	".json": &Format{ReadProperties, WriteProperties},
+	".yaml": &Format{ReadYAML, nil},
+	".yml":  &Format{ReadYAML, nil},
+	".toml": &Format{ReadTOML, nil},
+	".ini":  &Format{ReadINI, nil},
+
+	".properties": &Format{ReadJavaProperties, WriteJavaProperties},
+}
+
+// RegisterFormat registers the format used by ReadConfigFile and Save
+// for file names with the specified extension, such as ".json".
+func RegisterFormat(ext string, f *Format) {
+	formats[strings.ToLower(ext)] = f
+}
+
+// formatOf returns the format for a file name from its extension.
+// Files with an unknown extension are JSON.
+func formatOf(fname string) *Format {
+	f, ok := formats[strings.ToLower(filepath.Ext(fname))]
+	if !ok {
+		f = formats[".json"]
+	}
+	return f
+}
+
+// scalar converts an unquoted string from a text format into a property
+// value. The strings "true" and "false" are bools, decimal numbers are
+// numbers and any other string is returned unchanged.
+func scalar(s string) interface{} {
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if f, ok := number(s); ok {
+		return f
+	}
+	return s
+}
+
+// number parses a decimal, hexadecimal ("0x"), octal ("0o") or binary
+// ("0b") integer, or a decimal floating point number, optionally with
+// a sign and with underscores between digits. The number is returned
+// in decimal so that it can be written as JSON.
+func number(s string) (Number, bool) {
+	if s == "" || strings.IndexAny(s[len(s)-1:], "0123456789abcdefABCDEF") < 0 {
+		return "", false
+	}
+	sign := ""
+	t := s
+	switch t[0] {
+	case '-':
+		sign = "-"
+		t = t[1:]
+	case '+':
+		t = t[1:]
+	}
+	if strings.Index(t, "__") >= 0 || strings.HasPrefix(t, "_") {
+		return "", false
+	}
+	t = strings.Replace(t, "_", "", -1)
+	if t == "" {
+		return "", false
+	}
+	if len(t) > 2 && t[0] == '0' {
+		base := 0
+		switch t[1] {
+		case 'x', 'X':
+			base = 16
+		case 'o', 'O':
+			base = 8
+		case 'b', 'B':
+			base = 2
+		}
+		if base != 0 {
+			u, err := strconv.Btoui64(t[2:], base)
+			if err != nil {
+				return "", false
+			}
+			return Number(sign + strconv.Uitoa64(u)), true
+		}
+	}
+	if strings.IndexAny(t[:1], "0123456789.") < 0 || strings.IndexAny(t, "xX") >= 0 {
+		return "", false
+	}
+	f, err := strconv.Atof64(t)
+	if err != nil || math.IsInf(f, 0) {
+		return "", false
+	}
+	if !isJSONNumber(t) {
+		// Forms such as "1." or "007" are rewritten in JSON syntax.
+		t = strconv.Ftoa64(f, 'g', -1)
+	}
+	return Number(sign + t), true
+}
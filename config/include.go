@@ -0,0 +1,202 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config
+
+import (
+	"os"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"path/filepath"
+)
+
+// includeKey is the map key of an include directive in a config file.
+const includeKey = "$include"
+
+// maxIncludeDepth limits the nesting of included files, in case
+// a cycle is not detected because a file is named in two ways.
+const maxIncludeDepth = 32
+
+// readConfigFile reads a config file and the files it includes. A map
+// with the key "$include" is replaced by the maps read from the named
+// files, merged in order, with the other keys of the map merged last.
+// The value of the key is a file name or an array of file names, which
+// are relative to the including file and may be glob patterns. The name
+// of the file that supplied each value is recorded in the origins.
+func readConfigFile(fname string) (p *Properties, err os.Error) {
+	p, err = readFile(fname)
+	if err != nil {
+		return
+	}
+	origins := &originTree{name: fname, recorded: true}
+	if hasInclude(p.root) {
+		inc := &includer{origins, []string{filepath.Clean(fname)}}
+		p.root, err = inc.resolve(p.root, nil, fname)
+		if err != nil {
+			return nil, err
+		}
+	}
+	p.origins = origins
+	return p, nil
+}
+
+func readFile(fname string) (p *Properties, err os.Error) {
+	var f *os.File
+	f, err = os.Open(fname)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	p, err = formatOf(fname).Read(f)
+	if err != nil {
+		return nil, withFile(err, fname)
+	}
+	return p, nil
+}
+
+// hasInclude reports whether any map below v has an include directive.
+func hasInclude(v interface{}) bool {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if _, ok := t[includeKey]; ok {
+			return true
+		}
+		for _, elem := range t {
+			if hasInclude(elem) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, elem := range t {
+			if hasInclude(elem) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// includer resolves include directives, keeping the names of the
+// files being read to detect include cycles.
+type includer struct {
+	origins *originTree
+	stack   []string
+}
+
+// resolve returns a copy of v, read from fname, with its include
+// directives replaced by the included values, and records the file
+// that supplied each value copied.
+func (inc *includer) resolve(v interface{}, path []segment, fname string) (interface{}, os.Error) {
+	inc.origins.record(path, fname)
+	switch t := v.(type) {
+	case map[string]interface{}:
+		var result interface{} = make(map[string]interface{})
+		if directive, ok := t[includeKey]; ok {
+			names, err := includeFiles(directive, fname)
+			if err != nil {
+				return nil, err
+			}
+			for _, name := range names {
+				included, err := inc.include(name, path)
+				if err != nil {
+					return nil, err
+				}
+				result = merge(result, included, path, "", false, nil)
+			}
+			inc.origins.record(path, fname)
+		}
+		for _, key := range sortedKeys(t) {
+			if key == includeKey {
+				continue
+			}
+			elemPath := appendPath(path, segment{key, false})
+			elem, err := inc.resolve(t[key], elemPath, fname)
+			if err != nil {
+				return nil, err
+			}
+			m := result.(map[string]interface{})
+			m[key] = merge(m[key], elem, elemPath, "", false, nil)
+		}
+		return result, nil
+	case []interface{}:
+		a := make([]interface{}, len(t))
+		for i, elem := range t {
+			var err os.Error
+			a[i], err = inc.resolve(elem, appendPath(path, segment{strconv.Itoa(i), true}), fname)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return a, nil
+	}
+	return v, nil
+}
+
+// include reads an included file, which must contain a map,
+// and resolves its include directives.
+func (inc *includer) include(fname string, path []segment) (interface{}, os.Error) {
+	clean := filepath.Clean(fname)
+	for _, name := range inc.stack {
+		if name == clean {
+			cycle := strings.Join(append(inc.stack, clean), " -> ")
+			return nil, os.NewError("config file include cycle: " + cycle)
+		}
+	}
+	if len(inc.stack) >= maxIncludeDepth {
+		return nil, os.NewError("config file includes are nested too deeply: " + fname)
+	}
+
+	p, err := readFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.root.(map[string]interface{}); !ok {
+		return nil, os.NewError(fmt.Sprint("included config file is not a map: ", fname))
+	}
+	inc.stack = append(inc.stack, clean)
+	v, err := inc.resolve(p.root, path, fname)
+	inc.stack = inc.stack[:len(inc.stack)-1]
+	return v, err
+}
+
+// includeFiles returns the files named by an include directive in the
+// file fname. Patterns that match no files are ignored, but a file
+// name that is not a pattern must exist.
+func includeFiles(directive interface{}, fname string) ([]string, os.Error) {
+	var patterns []string
+	switch t := directive.(type) {
+	case string:
+		patterns = []string{t}
+	case []interface{}:
+		for _, elem := range t {
+			s, ok := elem.(string)
+			if !ok {
+				return nil, os.NewError(fmt.Sprint("include directive is not a file name or array of file names: ", fname))
+			}
+			patterns = append(patterns, s)
+		}
+	default:
+		return nil, os.NewError(fmt.Sprint("include directive is not a file name or array of file names: ", fname))
+	}
+
+	var names []string
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(fname), pattern)
+		}
+		if strings.IndexAny(pattern, "*?[") < 0 {
+			names = append(names, pattern)
+			continue
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(matches)
+		names = append(names, matches...)
+	}
+	return names, nil
+}
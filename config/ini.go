@@ -0,0 +1,94 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config
+
+import (
+	"os"
+	"io"
+	"strings"
+	"strconv"
+	"io/ioutil"
+)
+
+// ReadINI decodes INI data and stores it in a Properties structure.
+// Sections and keys are split on PropNameDelim into nested maps, so
+// the key "port" in section "[server.http]" is "server.http.port".
+// Keys and values are separated by '=' or ':' and lines starting with
+// ';' or '#' are comments. An unquoted value ends at a ';' or '#'
+// following a space or tab, which starts an inline comment. Unquoted
+// values are converted as bools and numbers where possible, double
+// quoted values are always strings.
+func ReadINI(r io.Reader) (*Properties, os.Error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	p, err := readINI(data)
+	if err != nil {
+		return nil, withText(err, data)
+	}
+	return p, nil
+}
+
+func readINI(data []byte) (p *Properties, err os.Error) {
+	var root interface{} = make(map[string]interface{})
+	var section []segment
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+
+		if line[0] == '[' {
+			if line[len(line)-1] != ']' {
+				return nil, syntaxError("ini", n+1, "section is missing ']'.")
+			}
+			section = split(strings.TrimSpace(line[1 : len(line)-1]))
+			if len(section) == 0 {
+				return nil, syntaxError("ini", n+1, "section name is empty.")
+			}
+			if _, err = lookup(root, nil, section); err != nil {
+				root, err = set(root, section, 0, make(map[string]interface{}))
+				if err != nil {
+					return nil, syntaxError("ini", n+1, "%s", err)
+				}
+			}
+			continue
+		}
+
+		i := strings.IndexAny(line, "=:")
+		if i < 0 {
+			return nil, syntaxError("ini", n+1, "key is missing '=' or ':'.")
+		}
+		key := split(strings.TrimSpace(line[:i]))
+		if len(key) == 0 {
+			return nil, syntaxError("ini", n+1, "key name is empty.")
+		}
+		var value interface{}
+		s := strings.TrimSpace(line[i+1:])
+		if len(s) > 1 && s[0] == '"' && s[len(s)-1] == '"' {
+			value, err = strconv.Unquote(s)
+			if err != nil {
+				return nil, syntaxError("ini", n+1, "invalid quoted value: %s", s)
+			}
+		} else {
+			value = scalar(iniValue(s))
+		}
+		root, err = set(root, joinPath(section, key), 0, value)
+		if err != nil {
+			return nil, syntaxError("ini", n+1, "%s", err)
+		}
+	}
+	return &Properties{root: root}, nil
+}
+
+// iniValue returns an unquoted value without its inline comment.
+func iniValue(s string) string {
+	for i := 1; i < len(s); i++ {
+		if (s[i] == ';' || s[i] == '#') && (s[i-1] == ' ' || s[i-1] == '\t') {
+			return strings.TrimSpace(s[:i])
+		}
+	}
+	return s
+}
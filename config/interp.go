@@ -0,0 +1,221 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config
+
+import (
+	"os"
+	"bytes"
+	"strings"
+	"strconv"
+	"io/ioutil"
+)
+
+// SetInterpolation enables or disables the expansion of references in
+// string values. A reference has one of the forms:
+//
+//	${server.host}      the value of another property
+//	${env:HOME}         the value of an environment variable
+//	${file:secret.txt}  the contents of a file, without a final newline
+//	${name:-fallback}   the fallback if name is not found or is empty
+//
+// Property names are relative to the top level properties, even for
+// Properties retrieved from p. References are expanded recursively
+// and a reference cycle is an error. The text "$${" is written as
+// "${" without starting a reference. Expanded strings are parsed into
+// the type requested by Bool, Int64, Float64 and the other accessors,
+// as values from the environment are. Properties retrieved from p
+// after it is called share the setting.
+func (p *Properties) SetInterpolation(on bool) {
+	p.interp = on
+}
+
+// RawString retrieves a string property value without expanding
+// references, or an error if not found.
+func (p *Properties) RawString(name ...interface{}) (string, os.Error) {
+	path, err := parseName(name...)
+	if err != nil {
+		return "", err
+	}
+	prop, err := p.lookup(path)
+	if err != nil {
+		return "", err
+	}
+	switch v := prop.(type) {
+	case string:
+		return v, nil
+	case envValue:
+		return string(v), nil
+	}
+	return "", p.mismatch(name, "string", prop)
+}
+
+// topLevel returns the properties that p was retrieved from.
+func (p *Properties) topLevel() *Properties {
+	if p.top != nil {
+		return p.top
+	}
+	return p
+}
+
+// interpolate expands the references in a string property value found
+// at path. A string that is changed by expansion is returned as an
+// envValue so that it is parsed like a value from the environment.
+func (p *Properties) interpolate(prop interface{}, path []segment) (interface{}, os.Error) {
+	var s string
+	switch v := prop.(type) {
+	case string:
+		s = v
+	case envValue:
+		s = string(v)
+	default:
+		return prop, nil
+	}
+	if strings.Index(s, "$") < 0 {
+		return prop, nil
+	}
+	x := &expander{p.topLevel(), []string{formatPath(joinPath(p.path, path))}}
+	e, err := x.expand(s)
+	if err != nil {
+		return nil, err
+	}
+	if e == s {
+		return prop, nil
+	}
+	return envValue(e), nil
+}
+
+// expander expands references, keeping the names of the
+// properties being expanded to detect reference cycles.
+type expander struct {
+	top   *Properties
+	stack []string
+}
+
+func (x *expander) expand(s string) (string, os.Error) {
+	var buf bytes.Buffer
+	for {
+		i := strings.Index(s, "$")
+		if i < 0 {
+			buf.WriteString(s)
+			break
+		}
+		buf.WriteString(s[:i])
+		s = s[i:]
+		switch {
+		case strings.HasPrefix(s, "$${"):
+			buf.WriteString("${")
+			s = s[3:]
+		case strings.HasPrefix(s, "${"):
+			end := closingBrace(s)
+			if end < 0 {
+				return "", os.NewError("interpolation reference is not terminated: " + s)
+			}
+			v, err := x.reference(s[2:end])
+			if err != nil {
+				return "", err
+			}
+			buf.WriteString(v)
+			s = s[end+1:]
+		default:
+			buf.WriteByte('$')
+			s = s[1:]
+		}
+	}
+	return buf.String(), nil
+}
+
+// closingBrace returns the index of the brace ending the reference
+// at the start of s, allowing for references nested in a fallback.
+func closingBrace(s string) int {
+	depth := 0
+	for i := 2; i < len(s); i++ {
+		switch {
+		case s[i] == '}' && depth == 0:
+			return i
+		case s[i] == '}':
+			depth--
+		case s[i] == '$' && i+1 < len(s) && s[i+1] == '{':
+			depth++
+			i++
+		}
+	}
+	return -1
+}
+
+// reference returns the expanded value of the reference ref.
+func (x *expander) reference(ref string) (string, os.Error) {
+	fallback, hasFallback := "", false
+	if i := strings.Index(ref, ":-"); i >= 0 {
+		ref, fallback, hasFallback = ref[:i], ref[i+2:], true
+	}
+
+	var v string
+	var found bool
+	var err os.Error
+	switch {
+	case strings.HasPrefix(ref, "env:"):
+		v, err = os.Getenverror(ref[4:])
+		found, err = err == nil, nil
+	case strings.HasPrefix(ref, "file:"):
+		var data []byte
+		data, err = ioutil.ReadFile(ref[5:])
+		if err == nil || hasFallback {
+			v = strings.TrimRight(string(data), "\r\n")
+			found, err = err == nil, nil
+		}
+	default:
+		v, found, err = x.property(ref)
+	}
+	if err != nil {
+		return "", err
+	}
+	if (!found || v == "") && hasFallback {
+		return x.expand(fallback)
+	}
+	if !found {
+		return "", os.NewError("interpolation reference not found: " + ref)
+	}
+	return v, nil
+}
+
+// property returns the expanded value of the property named by ref.
+func (x *expander) property(ref string) (string, bool, os.Error) {
+	path, err := parseName(ref)
+	if err != nil {
+		return "", false, err
+	}
+	name := formatPath(path)
+	for _, n := range x.stack {
+		if n == name {
+			cycle := strings.Join(append(x.stack, name), " -> ")
+			return "", false, os.NewError("interpolation reference cycle: " + cycle)
+		}
+	}
+	prop, err := x.top.lookup(path)
+	if err != nil {
+		return "", false, nil
+	}
+
+	switch v := prop.(type) {
+	case nil:
+		return "", true, nil
+	case bool:
+		return strconv.Btoa(v), true, nil
+	case Number:
+		return string(v), true, nil
+	case string:
+		return x.nested(name, v)
+	case envValue:
+		return x.nested(name, string(v))
+	}
+	return "", false, os.NewError("interpolation reference is not a string, number or bool: " + name)
+}
+
+// nested expands the value s of the property name.
+func (x *expander) nested(name, s string) (string, bool, os.Error) {
+	x.stack = append(x.stack, name)
+	s, err := x.expand(s)
+	x.stack = x.stack[:len(x.stack)-1]
+	return s, true, err
+}
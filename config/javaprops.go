@@ -0,0 +1,332 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config
+
+import (
+	"os"
+	"io"
+	"bytes"
+	"strings"
+	"strconv"
+	"io/ioutil"
+)
+
+// javaLine is a logical line of a Java properties file.
+type javaLine struct {
+	raw   string // the original text, including continuation lines
+	key   string // the property name, empty for comments and blank lines
+	sep   string // the separator between the key and value
+	value string
+}
+
+// ReadJavaProperties decodes Java properties data, lines of the form
+// "key=value" or "key: value", and stores it in a Properties structure.
+// Keys are split on PropNameDelim into nested maps, so "server.port"
+// is the key "port" in the map "server", and other characters such as
+// brackets are part of the keys. A key continuing past the value of
+// another key, such as "log.file" next to "log", is kept whole in the
+// deepest map, so "log.file" is a key of the same map as "log". All
+// values are strings.
+// Lines ending with a backslash are continued on the next line, and
+// lines starting with '#' or '!' are comments. The comments and order
+// of the keys are kept so WriteJavaProperties can reproduce the file.
+func ReadJavaProperties(r io.Reader) (*Properties, os.Error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	p, err := readJavaProperties(data)
+	if err != nil {
+		return nil, withText(err, data)
+	}
+	return p, nil
+}
+
+func readJavaProperties(data []byte) (p *Properties, err os.Error) {
+	root := make(map[string]interface{})
+	var doc []*javaLine
+	lines := strings.Split(string(data), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	for n := 0; n < len(lines); n++ {
+		num := n + 1
+		raw := strings.TrimRight(lines[n], "\r")
+		text := strings.TrimLeft(raw, " \t\f")
+		if text == "" || text[0] == '#' || text[0] == '!' {
+			doc = append(doc, &javaLine{raw: raw})
+			continue
+		}
+		for continued(text) && n+1 < len(lines) {
+			n++
+			next := strings.TrimRight(lines[n], "\r")
+			raw += "\n" + next
+			text = text[:len(text)-1] + strings.TrimLeft(next, " \t\f")
+		}
+		if continued(text) {
+			text = text[:len(text)-1]
+		}
+
+		line := &javaLine{raw: raw}
+		i := javaKeyEnd(text)
+		line.key = javaUnescape(text[:i])
+		j := i
+		for j < len(text) && isJavaSpace(text[j]) {
+			j++
+		}
+		if j < len(text) && (text[j] == '=' || text[j] == ':') {
+			j++
+			for j < len(text) && isJavaSpace(text[j]) {
+				j++
+			}
+		}
+		line.sep = text[i:j]
+		line.value = javaUnescape(text[j:])
+		if line.key == "" {
+			return nil, syntaxError("properties", num, "key is empty.")
+		}
+		doc = append(doc, line)
+		javaSet(root, strings.Split(line.key, PropNameDelim), line.value)
+	}
+	return &Properties{root: root, javaDoc: doc}, nil
+}
+
+// WriteJavaProperties encodes a Properties structure as Java properties,
+// one line for each value with the key formed from the property name.
+// If the properties were read by ReadJavaProperties, the comments, blank
+// lines and keys are written in their original order, and the original
+// text is kept for values that have not changed. Keys for new values
+// are written at the end in sorted order.
+func WriteJavaProperties(w io.Writer, p *Properties) os.Error {
+	var names []string
+	values := make(map[string]string)
+	p.rlock()
+	err := walk(p.root, nil, func(path []segment, v interface{}) os.Error {
+		s, ok := javaValue(v)
+		if ok {
+			name := javaName(path)
+			names = append(names, name)
+			values[name] = s
+		}
+		return nil
+	})
+	doc := p.javaDoc
+	p.runlock()
+	if err != nil {
+		return err
+	}
+	if len(p.path) > 0 {
+		doc = nil
+	}
+
+	var buf bytes.Buffer
+	written := make(map[string]bool)
+	for _, line := range doc {
+		if line.key == "" {
+			buf.WriteString(line.raw + "\n")
+			continue
+		}
+		name := line.key
+		value, ok := values[name]
+		if !ok || written[name] {
+			continue
+		}
+		written[name] = true
+		if value == line.value {
+			buf.WriteString(line.raw + "\n")
+		} else {
+			sep := line.sep
+			if sep == "" {
+				sep = "="
+			}
+			buf.WriteString(javaEscape(line.key, true) + sep + javaEscape(value, false) + "\n")
+		}
+	}
+	for _, name := range names {
+		if !written[name] {
+			buf.WriteString(javaEscape(name, true) + "=" + javaEscape(values[name], false) + "\n")
+		}
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// javaSet stores a value at the path of keys in nested maps below m.
+// A key continuing past a value is kept whole, joined with
+// PropNameDelim, in the deepest map, and a value replacing a map moves
+// the values of the map into m, with their keys joined in the same way.
+// So "log.file" is a key of the same map as "log" whichever is set first.
+func javaSet(m map[string]interface{}, keys []string, value string) {
+	for i, key := range keys[:len(keys)-1] {
+		child, ok := m[key]
+		if !ok {
+			child = make(map[string]interface{})
+			m[key] = child
+		}
+		cm, ok := child.(map[string]interface{})
+		if !ok {
+			m[strings.Join(keys[i:], PropNameDelim)] = value
+			return
+		}
+		m = cm
+	}
+	key := keys[len(keys)-1]
+	if cm, ok := m[key].(map[string]interface{}); ok {
+		javaFlatten(m, key, cm)
+	}
+	m[key] = value
+}
+
+// javaFlatten stores the values below the map cm in m, with their keys
+// joined to prefix with PropNameDelim.
+func javaFlatten(m map[string]interface{}, prefix string, cm map[string]interface{}) {
+	for key, v := range cm {
+		name := prefix + PropNameDelim + key
+		if vm, ok := v.(map[string]interface{}); ok {
+			javaFlatten(m, name, vm)
+		} else {
+			m[name] = v
+		}
+	}
+}
+
+// javaName returns the Java properties key of a path, its keys and
+// indices joined with PropNameDelim.
+func javaName(path []segment) string {
+	keys := make([]string, len(path))
+	for i, seg := range path {
+		keys[i] = seg.key
+	}
+	return strings.Join(keys, PropNameDelim)
+}
+
+// javaValue formats a scalar property value as a string.
+func javaValue(v interface{}) (string, bool) {
+	switch t := v.(type) {
+	case nil:
+		return "", true
+	case string:
+		return t, true
+	case bool:
+		return strconv.Btoa(t), true
+	case Number:
+		return string(t), true
+	}
+	return "", false
+}
+
+// continued reports whether a line ends with an odd number of backslashes.
+func continued(s string) bool {
+	n := 0
+	for i := len(s) - 1; i >= 0 && s[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+func isJavaSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\f'
+}
+
+// javaKeyEnd returns the index of the first unescaped
+// separator or whitespace, which ends the key.
+func javaKeyEnd(s string) int {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\':
+			i++
+		case c == '=' || c == ':' || isJavaSpace(c):
+			return i
+		}
+	}
+	return len(s)
+}
+
+func javaUnescape(s string) string {
+	if strings.IndexRune(s, '\\') < 0 {
+		return s
+	}
+	var buf bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i == len(s)-1 {
+			buf.WriteByte(c)
+			continue
+		}
+		i++
+		switch c = s[i]; c {
+		case 't':
+			buf.WriteByte('\t')
+		case 'n':
+			buf.WriteByte('\n')
+		case 'r':
+			buf.WriteByte('\r')
+		case 'f':
+			buf.WriteByte('\f')
+		case 'u':
+			if u, ok := javaCodeUnit(s, i); ok {
+				i += 4
+				// Characters outside the BMP are written as two escapes of
+				// a UTF-16 surrogate pair.
+				if lo, ok := javaCodeUnit(s, i+2); ok && s[i+1] == '\\' &&
+					0xD800 <= u && u < 0xDC00 && 0xDC00 <= lo && lo < 0xE000 {
+					u = (u-0xD800)<<10 + (lo - 0xDC00) + 0x10000
+					i += 6
+				}
+				buf.WriteRune(u)
+				break
+			}
+			buf.WriteByte(c)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String()
+}
+
+// javaCodeUnit returns the UTF-16 code unit of the four hexadecimal
+// digits following the 'u' of an escape at s[i].
+func javaCodeUnit(s string, i int) (int, bool) {
+	if i < 0 || i+4 >= len(s) || s[i] != 'u' {
+		return 0, false
+	}
+	u, err := strconv.Btoui64(s[i+1:i+5], 16)
+	return int(u), err == nil
+}
+
+func javaEscape(s string, key bool) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '=', ':':
+			if key {
+				buf.WriteByte('\\')
+			}
+			buf.WriteByte(c)
+		case '#', '!':
+			if i == 0 {
+				buf.WriteByte('\\')
+			}
+			buf.WriteByte(c)
+		case ' ':
+			if key || i == 0 {
+				buf.WriteByte('\\')
+			}
+			buf.WriteByte(c)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String()
+}
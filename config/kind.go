@@ -0,0 +1,126 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config
+
+import (
+	"os"
+	"strconv"
+)
+
+// Kind is the JSON type of a property value.
+type Kind int
+
+const (
+	NullKind Kind = iota
+	BoolKind
+	NumberKind
+	StringKind
+	ArrayKind
+	MapKind
+)
+
+func (k Kind) String() string {
+	switch k {
+	case NullKind:
+		return "null"
+	case BoolKind:
+		return "bool"
+	case NumberKind:
+		return "number"
+	case StringKind:
+		return "string"
+	case ArrayKind:
+		return "array"
+	case MapKind:
+		return "map"
+	}
+	return "Kind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Kind retrieves the kind of a property value or an error if not
+// found. A value from the environment is a string.
+func (p *Properties) Kind(name ...interface{}) (Kind, os.Error) {
+	prop, err := p.Property(name...)
+	if err != nil {
+		return NullKind, err
+	}
+	return kindOf(prop), nil
+}
+
+func kindOf(prop interface{}) Kind {
+	switch prop.(type) {
+	case bool:
+		return BoolKind
+	case Number:
+		return NumberKind
+	case string, envValue:
+		return StringKind
+	case []interface{}:
+		return ArrayKind
+	case map[string]interface{}:
+		return MapKind
+	}
+	return NullKind
+}
+
+// Keys retrieves the keys of a map property value in sorted order,
+// or an error if not found or not a map. Without a name, it retrieves
+// the keys of p. A map is not replaced by a value from the environment.
+func (p *Properties) Keys(name ...interface{}) ([]string, os.Error) {
+	path, err := parseName(name...)
+	if err != nil {
+		return nil, err
+	}
+	p.rlock()
+	defer p.runlock()
+	prop, err := p.container(path)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := prop.(map[string]interface{})
+	if !ok {
+		return nil, p.mismatch(name, "map", prop)
+	}
+	return sortedKeys(m), nil
+}
+
+// Len retrieves the number of keys of a map property value or elements
+// of an array property value, or an error if not found or not a map or
+// array. Without a name, it retrieves the length of p. A map or array
+// is not replaced by a value from the environment.
+func (p *Properties) Len(name ...interface{}) (int, os.Error) {
+	path, err := parseName(name...)
+	if err != nil {
+		return 0, err
+	}
+	p.rlock()
+	defer p.runlock()
+	prop, err := p.container(path)
+	if err != nil {
+		return 0, err
+	}
+	switch v := prop.(type) {
+	case map[string]interface{}:
+		return len(v), nil
+	case []interface{}:
+		return len(v), nil
+	}
+	return 0, p.mismatch(name, "map or array", prop)
+}
+
+// container retrieves the map or array at path in p. The environment
+// overlay only replaces values that are not maps or arrays, so that a
+// variable named like a map does not hide it. It is called with the
+// lock held.
+func (p *Properties) container(path []segment) (interface{}, os.Error) {
+	prop, err := lookup(p.root, p.path, path)
+	switch prop.(type) {
+	case map[string]interface{}, []interface{}:
+		return prop, err
+	}
+	if v, ok := p.envLookup(joinPath(p.path, path)); ok {
+		return v, nil
+	}
+	return prop, err
+}
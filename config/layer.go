@@ -0,0 +1,288 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config
+
+import (
+	"os"
+	"io"
+	"strings"
+	"strconv"
+)
+
+// Layer is a named source of properties for NewLayered.
+type Layer struct {
+	Name  string
+	props *Properties
+}
+
+// NewLayer creates a Layer with the specified name from Properties.
+func NewLayer(name string, p *Properties) *Layer {
+	return &Layer{name, p}
+}
+
+// FileLayer creates a Layer named by the file from a config file.
+func FileLayer(fname string) (*Layer, os.Error) {
+	c, err := ReadConfigFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	return &Layer{fname, c.Properties}, nil
+}
+
+// ReaderLayer creates a Layer with the specified name from JSON data.
+func ReaderLayer(name string, r io.Reader) (*Layer, os.Error) {
+	p, err := ReadProperties(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Layer{name, p}, nil
+}
+
+// MapLayer creates a Layer with the specified name from a map of
+// values, which are converted the same as values given to Set.
+func MapLayer(name string, m map[string]interface{}) (*Layer, os.Error) {
+	root, err := normalize(m)
+	if err != nil {
+		return nil, err
+	}
+	return &Layer{name, &Properties{root: root}}, nil
+}
+
+// NewLayered creates Properties by merging layers in order, from lowest
+// to highest precedence, so a value in a later layer overrides the value
+// in an earlier layer. Maps are merged key by key. Arrays and all other
+// values are replaced, or if appendArrays is true, arrays in later layers
+// are appended to arrays in earlier layers. The layers are not modified.
+// The name of the layer that supplied a value is reported by Origin.
+func NewLayered(appendArrays bool, layers ...*Layer) *Properties {
+	p := &Properties{origins: new(originTree)}
+	for _, layer := range layers {
+		p.root = merge(p.root, layer.props.root, nil, layer.Name, appendArrays, p.origins)
+	}
+	return p
+}
+
+// Origin returns the name of the layer that supplied a property value.
+// The name is empty for values that were not supplied by a layer, such
+// as values stored with Set.
+func (p *Properties) Origin(name ...interface{}) (string, os.Error) {
+	path, err := parseName(name...)
+	if err != nil {
+		return "", err
+	}
+	p.rlock()
+	defer p.runlock()
+	_, err = lookup(p.root, p.path, path)
+	if err != nil {
+		return "", err
+	}
+	path, _ = resolveIndices(p.root, path)
+	return p.origins.get(joinPath(p.path, path)), nil
+}
+
+// merge combines src into dst and returns the result, recording the
+// origin of each value copied from src. Values from src are copied so
+// later changes to dst do not affect src.
+func merge(dst, src interface{}, path []segment, origin string, appendArrays bool, origins *originTree) interface{} {
+	switch s := src.(type) {
+	case map[string]interface{}:
+		if d, ok := dst.(map[string]interface{}); ok {
+			for key, elem := range s {
+				d[key] = merge(d[key], elem, appendPath(path, segment{key, false}), origin, appendArrays, origins)
+			}
+			return d
+		}
+	case []interface{}:
+		if d, ok := dst.([]interface{}); ok && appendArrays {
+			for _, elem := range s {
+				v, _ := normalize(elem)
+				origins.set(appendPath(path, segment{strconv.Itoa(len(d)), true}), origin)
+				d = append(d, v)
+			}
+			return d
+		}
+	}
+	v, _ := normalize(src)
+	origins.set(path, origin)
+	return v
+}
+
+// originTree records the names of the layers or files that supplied
+// property values, by the map keys and array indices of their paths.
+// The origin of a value is the name recorded at its path or at the
+// nearest path above it. Index and name segments are not distinguished
+// since either form may be used to retrieve an array element. The
+// methods do nothing on a nil tree.
+type originTree struct {
+	name     string
+	recorded bool
+	children map[string]*originTree
+}
+
+// node returns the node at path, or nil if there is none and create
+// is false.
+func (o *originTree) node(path []segment, create bool) *originTree {
+	for _, seg := range path {
+		child := o.children[seg.key]
+		if child == nil {
+			if !create {
+				return nil
+			}
+			if o.children == nil {
+				o.children = make(map[string]*originTree)
+			}
+			child = new(originTree)
+			o.children[seg.key] = child
+		}
+		o = child
+	}
+	return o
+}
+
+// clone returns a copy of the tree.
+func (o *originTree) clone() *originTree {
+	if o == nil {
+		return nil
+	}
+	c := &originTree{name: o.name, recorded: o.recorded}
+	if o.children != nil {
+		c.children = make(map[string]*originTree, len(o.children))
+		for key, child := range o.children {
+			c.children[key] = child.clone()
+		}
+	}
+	return c
+}
+
+// get returns the origin of the value at path.
+func (o *originTree) get(path []segment) string {
+	var name string
+	for i := 0; o != nil; i++ {
+		if o.recorded {
+			name = o.name
+		}
+		if i == len(path) {
+			break
+		}
+		o = o.children[path[i].key]
+	}
+	return name
+}
+
+// record records the origin of the value at path, keeping the
+// origins recorded for values below it.
+func (o *originTree) record(path []segment, name string) {
+	if o == nil {
+		return
+	}
+	n := o.node(path, true)
+	n.name, n.recorded = name, true
+}
+
+// set records the origin of the value at path,
+// forgetting the origins of any values below it.
+func (o *originTree) set(path []segment, name string) {
+	if o == nil {
+		return
+	}
+	n := o.node(path, true)
+	n.name, n.recorded, n.children = name, true, nil
+}
+
+// forget forgets the origins of the value at path
+// and of any values below it.
+func (o *originTree) forget(path []segment) {
+	if o == nil {
+		return
+	}
+	if len(path) == 0 {
+		*o = originTree{}
+		return
+	}
+	parent := o.node(path[:len(path)-1], false)
+	if parent != nil && parent.children != nil {
+		parent.children[path[len(path)-1].key] = nil, false
+	}
+}
+
+// insert records the origin of an array element inserted at path,
+// shifting the origins of the following elements up.
+func (o *originTree) insert(path []segment, name string) {
+	idx, _ := strconv.Atoi(path[len(path)-1].key)
+	o.shift(path[:len(path)-1], idx, 1)
+	o.set(path, name)
+}
+
+// remove forgets the origins of an array element removed at path,
+// shifting the origins of the following elements down.
+func (o *originTree) remove(path []segment) {
+	idx, _ := strconv.Atoi(path[len(path)-1].key)
+	o.forget(path)
+	o.shift(path[:len(path)-1], idx+1, -1)
+}
+
+// shift moves the origins of the elements of the array at path from
+// index from onwards by delta.
+func (o *originTree) shift(path []segment, from, delta int) {
+	if o == nil {
+		return
+	}
+	n := o.node(path, false)
+	if n == nil || n.children == nil {
+		return
+	}
+	moved := make(map[string]*originTree)
+	for key, child := range n.children {
+		if idx, err := strconv.Atoi(key); err == nil && idx >= from {
+			n.children[key] = nil, false
+			moved[strconv.Itoa(idx+delta)] = child
+		}
+	}
+	for key, child := range moved {
+		n.children[key] = child
+	}
+}
+
+// resolveIndices returns path with the negative indices of the arrays
+// below root replaced by the indices they select, and whether the last
+// segment selects an element of an array.
+func resolveIndices(root interface{}, path []segment) ([]segment, bool) {
+	resolved := make([]segment, len(path))
+	copy(resolved, path)
+	elem := false
+	cur := root
+	for i, seg := range path {
+		elem = false
+		switch v := cur.(type) {
+		case map[string]interface{}:
+			cur = v[seg.key]
+		case []interface{}:
+			idx, ok := arrayIndex(seg.key, len(v))
+			if !ok || isSlice(seg) {
+				cur = nil
+				continue
+			}
+			resolved[i].key = strconv.Itoa64(idx)
+			elem = true
+			cur = nil
+			if idx >= 0 && idx < int64(len(v)) {
+				cur = v[idx]
+			}
+		default:
+			cur = nil
+		}
+	}
+	return resolved, elem
+}
+
+// originKey returns a key identifying a path. Index and name segments
+// are not distinguished since either form may be used to retrieve an
+// array element.
+func originKey(path []segment) string {
+	keys := make([]string, len(path))
+	for i, seg := range path {
+		keys[i] = seg.key
+	}
+	return strings.Join(keys, "\x00")
+}
@@ -0,0 +1,205 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config
+
+import (
+	"os"
+	"big"
+	"math"
+	"strings"
+	"strconv"
+)
+
+// Number is a number property value. It is stored as the decimal
+// text from the config file so that no precision is lost, in
+// particular for integers that do not fit exactly in a float64.
+type Number string
+
+// maxIntDigits limits the digits of an integer written with an exponent.
+const maxIntDigits = 1000
+
+func (n Number) String() string {
+	return string(n)
+}
+
+// Float64 returns the number as a float64, rounded if necessary.
+func (n Number) Float64() (float64, os.Error) {
+	return strconv.Atof64(string(n))
+}
+
+// Int64 returns the number as an int64. An error is returned
+// if the number is not an integer or is out of range.
+func (n Number) Int64() (int64, os.Error) {
+	s, err := n.integer()
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.Atoi64(s)
+	if err != nil {
+		return 0, os.NewError("number is out of range for int64: " + string(n))
+	}
+	return i, nil
+}
+
+// Int32 returns the number as an int32. An error is returned
+// if the number is not an integer or is out of range.
+func (n Number) Int32() (int32, os.Error) {
+	i, err := n.Int64()
+	if err != nil {
+		return 0, err
+	}
+	if i < math.MinInt32 || i > math.MaxInt32 {
+		return 0, os.NewError("number is out of range for int32: " + string(n))
+	}
+	return int32(i), nil
+}
+
+// Uint64 returns the number as a uint64. An error is returned
+// if the number is not an integer or is out of range.
+func (n Number) Uint64() (uint64, os.Error) {
+	s, err := n.integer()
+	if err != nil {
+		return 0, err
+	}
+	u, err := strconv.Atoui64(s)
+	if err != nil {
+		return 0, os.NewError("number is out of range for uint64: " + string(n))
+	}
+	return u, nil
+}
+
+// BigInt returns the number as a big.Int. An error is
+// returned if the number is not an integer.
+func (n Number) BigInt() (*big.Int, os.Error) {
+	s, err := n.integer()
+	if err != nil {
+		return nil, err
+	}
+	i, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return nil, os.NewError("number is not an integer: " + string(n))
+	}
+	return i, nil
+}
+
+// compareNumbers returns -1, 0 or 1 as a is less than, equal to or
+// greater than b, or false if either is not a valid number. Integers
+// are compared exactly, other numbers as float64.
+func compareNumbers(a, b Number) (int, bool) {
+	x, xerr := a.BigInt()
+	y, yerr := b.BigInt()
+	if xerr == nil && yerr == nil {
+		return x.Cmp(y), true
+	}
+	xf, xerr := a.Float64()
+	yf, yerr := b.Float64()
+	if xerr != nil || yerr != nil || xf != xf || yf != yf {
+		return 0, false
+	}
+	switch {
+	case xf < yf:
+		return -1, true
+	case xf > yf:
+		return 1, true
+	}
+	return 0, true
+}
+
+// integer returns the number as decimal integer digits with an optional
+// minus sign. Fractions and exponents are allowed if the value is still
+// an integer, so "2.0" and "1e3" are integers but "3.7" is not.
+func (n Number) integer() (string, os.Error) {
+	s := string(n)
+	neg := false
+	if len(s) > 0 && (s[0] == '-' || s[0] == '+') {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+
+	exp := 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.Atoi(s[i+1:])
+		if err != nil {
+			return "", os.NewError("number is not valid: " + string(n))
+		}
+		s, exp = s[:i], e
+	}
+	digits, frac := s, ""
+	if i := strings.Index(s, "."); i >= 0 {
+		digits, frac = s[:i], s[i+1:]
+	}
+	if !isDigits(digits+frac) || len(digits+frac) == 0 {
+		return "", os.NewError("number is not an integer: " + string(n))
+	}
+
+	point := len(digits) + exp
+	digits += frac
+	switch {
+	case point > maxIntDigits:
+		return "", os.NewError("number is out of range: " + string(n))
+	case point < 0:
+		point = 0
+	case point > len(digits):
+		digits += strings.Repeat("0", point-len(digits))
+	}
+	if strings.Trim(digits[point:], "0") != "" {
+		return "", os.NewError("number is not an integer: " + string(n))
+	}
+	digits = strings.TrimLeft(digits[:point], "0")
+	if digits == "" {
+		return "0", nil
+	}
+	if neg {
+		digits = "-" + digits
+	}
+	return digits, nil
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isJSONNumber reports whether s is a number in JSON syntax.
+func isJSONNumber(s string) bool {
+	i := 0
+	if i < len(s) && s[i] == '-' {
+		i++
+	}
+	start := i
+	for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+		i++
+	}
+	if i == start || (s[start] == '0' && i > start+1) {
+		return false
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		start = i
+		for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+			i++
+		}
+		if i == start {
+			return false
+		}
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		start = i
+		for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+			i++
+		}
+		if i == start {
+			return false
+		}
+	}
+	return i == len(s)
+}
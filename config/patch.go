@@ -0,0 +1,532 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config
+
+import (
+	"os"
+	"io"
+	"fmt"
+	"bytes"
+	"strconv"
+	"strings"
+	"io/ioutil"
+)
+
+// WritePatch writes the changes from a to b as a JSON Patch document
+// (RFC 6902), an array of "add", "remove" and "replace" operations that
+// turns the values of a into the values of b. Unlike Diff, an added or
+// removed map or array is a single operation. Values that are equal
+// under the options, which may be nil, are not changed, and an array
+// that differs when order is ignored is replaced.
+func WritePatch(w io.Writer, a, b *Properties, opts *DiffOptions) os.Error {
+	defer rlockPair(a, b)()
+	var buf bytes.Buffer
+	err := encode(&buf, patchOps(a.root, b.root, nil, []interface{}{}, opts), "")
+	if err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// patchOps appends the JSON Patch operations that turn a into b,
+// below path, to ops.
+func patchOps(a, b interface{}, path []segment, ops []interface{}, opts *DiffOptions) []interface{} {
+	if opts.same(a, b) {
+		return ops
+	}
+	switch x := a.(type) {
+	case map[string]interface{}:
+		if y, ok := b.(map[string]interface{}); ok {
+			keys := make(map[string]interface{}, len(x)+len(y))
+			for key := range x {
+				keys[key] = nil
+			}
+			for key := range y {
+				keys[key] = nil
+			}
+			for _, key := range sortedKeys(keys) {
+				kpath := appendPath(path, segment{key, false})
+				xv, xok := x[key]
+				yv, yok := y[key]
+				switch {
+				case !yok:
+					ops = append(ops, patchOp("remove", kpath, nil))
+				case !xok:
+					ops = append(ops, patchOp("add", kpath, yv))
+				default:
+					ops = patchOps(xv, yv, kpath, ops, opts)
+				}
+			}
+			return ops
+		}
+	case []interface{}:
+		if y, ok := b.([]interface{}); ok && (opts == nil || !opts.IgnoreOrder) {
+			n := len(x)
+			if len(y) < n {
+				n = len(y)
+			}
+			for i := 0; i < n; i++ {
+				ops = patchOps(x[i], y[i], appendPath(path, segment{strconv.Itoa(i), true}), ops, opts)
+			}
+			for i := len(x) - 1; i >= n; i-- {
+				ops = append(ops, patchOp("remove", appendPath(path, segment{strconv.Itoa(i), true}), nil))
+			}
+			for i := n; i < len(y); i++ {
+				ops = append(ops, patchOp("add", appendPath(path, segment{strconv.Itoa(i), true}), y[i]))
+			}
+			return ops
+		}
+	}
+	return append(ops, patchOp("replace", path, b))
+}
+
+// patchOp returns a JSON Patch operation, with a value unless
+// the operation is "remove".
+func patchOp(op string, path []segment, value interface{}) interface{} {
+	m := map[string]interface{}{"op": op, "path": pointer(path)}
+	if op != "remove" {
+		m["value"] = value
+	}
+	return m
+}
+
+// pointer formats a path as a JSON pointer (RFC 6901).
+func pointer(path []segment) string {
+	var s string
+	for _, seg := range path {
+		s += "/" + strings.Replace(strings.Replace(seg.key, "~", "~0", -1), "/", "~1", -1)
+	}
+	return s
+}
+
+// PatchError is returned when an operation of a JSON Patch cannot be
+// applied, in which case none of the operations are applied.
+type PatchError struct {
+	Index int    // the index of the operation in the patch
+	Op    string // the operation, such as "add" or "test"
+	Name  string // the property name of the operation path
+	Err   os.Error
+}
+
+func (e *PatchError) String() string {
+	s := fmt.Sprintf("patch operation %d (%s", e.Index, e.Op)
+	if e.Name != "" {
+		s += " " + e.Name
+	}
+	return s + ") failed: " + e.Err.String()
+}
+
+// ApplyPatch reads a JSON Patch document (RFC 6902) and applies its
+// "add", "remove", "replace", "move", "copy" and "test" operations to
+// the property values in order. The JSON pointers in the operations
+// are relative to p, so "/server/port" is the property "server.port",
+// and the values patched are seen by the properties p was retrieved from.
+// The operations are applied atomically: if one fails, a *PatchError
+// is returned for it and the property values are not changed.
+func (p *Properties) ApplyPatch(r io.Reader) os.Error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	v, err := decodeJSON(data)
+	if err != nil {
+		return withText(err, data)
+	}
+	ops, ok := v.([]interface{})
+	if !ok {
+		return os.NewError("JSON Patch is not an array of operations.")
+	}
+
+	p.wlock()
+	defer p.wunlock()
+	p.refresh()
+	doc, _ := normalize(p.root)
+	origins := p.origins.clone()
+	for i, elem := range ops {
+		op, err := parseOperation(elem)
+		if err == nil {
+			doc, err = op.apply(doc, origins, p.path)
+		}
+		if err != nil {
+			e := &PatchError{Index: i, Err: err}
+			if op != nil {
+				e.Op = op.op
+				e.Name = formatPath(op.path)
+			}
+			return e
+		}
+	}
+	if err := p.store(doc); err != nil {
+		return err
+	}
+	if p.origins != nil {
+		*p.origins = *origins
+	}
+	return nil
+}
+
+// ApplyMergePatch reads a JSON Merge Patch document (RFC 7396) and
+// merges it into the property values. A map in the patch is merged
+// into the map at the same place, creating it if needed, with a null
+// value removing a key. Any other value in the patch replaces the
+// value at the same place.
+func (p *Properties) ApplyMergePatch(r io.Reader) os.Error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	patch, err := decodeJSON(data)
+	if err != nil {
+		return withText(err, data)
+	}
+	p.wlock()
+	defer p.wunlock()
+	p.refresh()
+	return p.store(mergePatch(p.root, patch, joinPath(p.path, nil), p.origins))
+}
+
+// mergePatch merges a merge patch into target at path and returns the
+// result, recording the values replaced in the origins.
+func mergePatch(target, patch interface{}, path []segment, origins *originTree) interface{} {
+	m, ok := patch.(map[string]interface{})
+	if !ok {
+		origins.set(path, "")
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = make(map[string]interface{})
+		origins.set(path, "")
+	}
+	for _, key := range sortedKeys(m) {
+		kpath := appendPath(path, segment{key, false})
+		if m[key] == nil {
+			if _, ok := t[key]; ok {
+				t[key] = nil, false
+				origins.forget(kpath)
+			}
+			continue
+		}
+		t[key] = mergePatch(t[key], m[key], kpath, origins)
+	}
+	return t
+}
+
+// operation is a JSON Patch operation.
+type operation struct {
+	op    string
+	path  []segment
+	from  []segment // the source of "move" and "copy"
+	value interface{}
+}
+
+// parseOperation parses an operation of a JSON Patch. If the
+// operation is not valid, the operation is returned with the error
+// if its name and path could be parsed.
+func parseOperation(v interface{}) (*operation, os.Error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, os.NewError("operation is not a map.")
+	}
+	name, ok := m["op"].(string)
+	if !ok {
+		return nil, os.NewError("operation has no \"op\" member.")
+	}
+	ptr, ok := m["path"].(string)
+	if !ok {
+		return nil, os.NewError("operation has no \"path\" member.")
+	}
+	path, err := parsePointer(ptr)
+	if err != nil {
+		return nil, err
+	}
+	op := &operation{op: name, path: path}
+	switch name {
+	case "add", "replace", "test":
+		if op.value, ok = m["value"]; !ok {
+			return op, os.NewError("operation has no \"value\" member.")
+		}
+	case "move", "copy":
+		ptr, ok := m["from"].(string)
+		if !ok {
+			return op, os.NewError("operation has no \"from\" member.")
+		}
+		if op.from, err = parsePointer(ptr); err != nil {
+			return op, err
+		}
+	case "remove":
+	default:
+		return op, os.NewError("operation is not known: " + name)
+	}
+	return op, nil
+}
+
+// apply applies the operation to doc and returns the result, which
+// differs from doc if the root or an array was replaced. The origins
+// of the values changed, whose paths are below base, are updated.
+func (op *operation) apply(doc interface{}, origins *originTree, base []segment) (interface{}, os.Error) {
+	var err os.Error
+	switch op.op {
+	case "add":
+		doc, err = patchAdd(doc, op.path, op.value)
+	case "remove":
+		elem := patchElement(doc, op.path)
+		doc, err = patchRemove(doc, op.path)
+		if err == nil {
+			forgetPatched(origins, joinPath(base, op.path), elem)
+		}
+		return doc, err
+	case "replace":
+		doc, err = patchReplace(doc, op.path, op.value)
+		if err == nil {
+			origins.set(joinPath(base, op.path), "")
+		}
+		return doc, err
+	case "move":
+		if pointer(op.from) == pointer(op.path) {
+			return doc, nil
+		}
+		if strings.HasPrefix(pointer(op.path), pointer(op.from)+"/") {
+			return nil, os.NewError("property cannot be moved into itself: " + formatPath(op.from))
+		}
+		var v interface{}
+		v, err = patchGet(doc, op.from)
+		if err != nil {
+			return nil, err
+		}
+		elem := patchElement(doc, op.from)
+		doc, err = patchRemove(doc, op.from)
+		if err != nil {
+			return nil, err
+		}
+		forgetPatched(origins, joinPath(base, op.from), elem)
+		doc, err = patchAdd(doc, op.path, v)
+	case "copy":
+		var v interface{}
+		v, err = patchGet(doc, op.from)
+		if err != nil {
+			return nil, err
+		}
+		v, _ = normalize(v)
+		doc, err = patchAdd(doc, op.path, v)
+	default:
+		var v interface{}
+		v, err = patchGet(doc, op.path)
+		if err != nil {
+			return nil, err
+		}
+		if !(&DiffOptions{NumericEqual: true}).same(v, op.value) {
+			return nil, os.NewError("property value is not equal to the test value: " + formatPath(op.path))
+		}
+		return doc, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The value was added by "add", "move" or "copy".
+	path := op.path
+	if len(path) > 0 {
+		parent, _ := patchGet(doc, path[:len(path)-1])
+		if a, ok := parent.([]interface{}); ok {
+			if path[len(path)-1].key == "-" {
+				path = appendPath(path[:len(path)-1], segment{strconv.Itoa(len(a) - 1), true})
+			}
+			origins.insert(joinPath(base, path), "")
+			return doc, nil
+		}
+	}
+	origins.set(joinPath(base, path), "")
+	return doc, nil
+}
+
+// patchElement reports whether the value at path in doc is an element
+// of an array.
+func patchElement(doc interface{}, path []segment) bool {
+	if len(path) == 0 {
+		return false
+	}
+	parent, _ := patchGet(doc, path[:len(path)-1])
+	_, ok := parent.([]interface{})
+	return ok
+}
+
+// forgetPatched forgets the origins of a value removed from path,
+// shifting the origins of the following elements if it was an element
+// of an array.
+func forgetPatched(origins *originTree, path []segment, elem bool) {
+	if elem {
+		origins.remove(path)
+	} else {
+		origins.forget(path)
+	}
+}
+
+// patchGet returns the value at path in doc.
+func patchGet(doc interface{}, path []segment) (interface{}, os.Error) {
+	cur := doc
+	for i := range path {
+		var err os.Error
+		cur, err = patchChild(cur, path, i)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return cur, nil
+}
+
+func patchAdd(doc interface{}, path []segment, value interface{}) (interface{}, os.Error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return patchParent(doc, path, 0, func(parent interface{}, i int) (interface{}, os.Error) {
+		switch v := parent.(type) {
+		case map[string]interface{}:
+			v[path[i].key] = value
+			return v, nil
+		case []interface{}:
+			idx, err := patchIndex(v, path, i, true)
+			if err != nil {
+				return nil, err
+			}
+			v = append(v, nil)
+			copy(v[idx+1:], v[idx:])
+			v[idx] = value
+			return v, nil
+		}
+		return nil, containerMismatch(path, i, parent)
+	})
+}
+
+func patchRemove(doc interface{}, path []segment) (interface{}, os.Error) {
+	if len(path) == 0 {
+		return nil, os.NewError("property name is required, cannot remove root property.")
+	}
+	return patchParent(doc, path, 0, func(parent interface{}, i int) (interface{}, os.Error) {
+		if _, err := patchChild(parent, path, i); err != nil {
+			return nil, err
+		}
+		switch v := parent.(type) {
+		case map[string]interface{}:
+			v[path[i].key] = nil, false
+			return v, nil
+		case []interface{}:
+			idx, _ := patchIndex(v, path, i, false)
+			copy(v[idx:], v[idx+1:])
+			return v[:len(v)-1], nil
+		}
+		return nil, containerMismatch(path, i, parent)
+	})
+}
+
+func patchReplace(doc interface{}, path []segment, value interface{}) (interface{}, os.Error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return patchParent(doc, path, 0, func(parent interface{}, i int) (interface{}, os.Error) {
+		if _, err := patchChild(parent, path, i); err != nil {
+			return nil, err
+		}
+		switch v := parent.(type) {
+		case map[string]interface{}:
+			v[path[i].key] = value
+			return v, nil
+		case []interface{}:
+			idx, _ := patchIndex(v, path, i, false)
+			v[idx] = value
+			return v, nil
+		}
+		return nil, containerMismatch(path, i, parent)
+	})
+}
+
+// patchParent calls fn for the parent of the value at path below cur,
+// with the index of the last segment, and stores the updated parent.
+func patchParent(cur interface{}, path []segment, i int, fn func(parent interface{}, i int) (interface{}, os.Error)) (interface{}, os.Error) {
+	if i == len(path)-1 {
+		return fn(cur, i)
+	}
+	child, err := patchChild(cur, path, i)
+	if err != nil {
+		return nil, err
+	}
+	child, err = patchParent(child, path, i+1, fn)
+	if err != nil {
+		return nil, err
+	}
+	switch v := cur.(type) {
+	case map[string]interface{}:
+		v[path[i].key] = child
+	case []interface{}:
+		idx, _ := patchIndex(v, path, i, false)
+		v[idx] = child
+	}
+	return cur, nil
+}
+
+// patchChild returns the value selected by path[i] in cur.
+func patchChild(cur interface{}, path []segment, i int) (interface{}, os.Error) {
+	switch v := cur.(type) {
+	case map[string]interface{}:
+		child, ok := v[path[i].key]
+		if !ok {
+			return nil, &NotFoundError{formatPath(path), formatPath(path[:i+1])}
+		}
+		return child, nil
+	case []interface{}:
+		idx, err := patchIndex(v, path, i, false)
+		if err != nil {
+			return nil, err
+		}
+		return v[idx], nil
+	}
+	return nil, containerMismatch(path, i, cur)
+}
+
+// patchIndex returns the index in the array v selected by path[i],
+// which may be the length of the array, written "-", when adding.
+func patchIndex(v []interface{}, path []segment, i int, adding bool) (int, os.Error) {
+	key := path[i].key
+	if adding && key == "-" {
+		return len(v), nil
+	}
+	if !path[i].index || key == "-" || (len(key) > 1 && key[0] == '0') {
+		return 0, &TypeMismatchError{formatPath(path), formatPath(path[:i]), "map", "array"}
+	}
+	idx, err := strconv.Atoi64(key)
+	n := int64(len(v))
+	if adding {
+		n++
+	}
+	if err != nil || idx >= n {
+		return 0, &IndexOutOfRangeError{formatPath(path), formatPath(path[:i+1]), idx, len(v)}
+	}
+	return int(idx), nil
+}
+
+// parsePointer parses a JSON pointer (RFC 6901) into a path. Tokens
+// that are array indices are index segments, which also select map
+// keys.
+func parsePointer(s string) ([]segment, os.Error) {
+	if s == "" {
+		return nil, nil
+	}
+	if s[0] != '/' {
+		return nil, os.NewError("JSON pointer does not start with '/': " + s)
+	}
+	tokens := strings.Split(s[1:], "/")
+	path := make([]segment, len(tokens))
+	for i, tok := range tokens {
+		for j := 0; j < len(tok); j++ {
+			if tok[j] == '~' && (j+1 == len(tok) || (tok[j+1] != '0' && tok[j+1] != '1')) {
+				return nil, os.NewError("JSON pointer has an invalid escape: " + s)
+			}
+		}
+		key := strings.Replace(strings.Replace(tok, "~1", "/", -1), "~0", "~", -1)
+		path[i] = segment{key, key == "-" || (key != "" && isDigits(key))}
+	}
+	return path, nil
+}
@@ -0,0 +1,197 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config
+
+import (
+	"os"
+	"bytes"
+	"strings"
+	"strconv"
+)
+
+// parsePath parses a property name into a path of segments. Segments
+// are separated by PropNameDelim and may be followed by any number of
+// bracketed selectors:
+//
+//	a.b[2]          element 2 of the array b
+//	a.b[-1]         the last element of b
+//	m[1][2]         element 2 of element 1 of m
+//	list[1:3]       a copy of elements 1 and 2 of list, either bound may
+//	                be omitted or negative, and both are clamped to the array
+//	hosts["db.example.com"]
+//	                the key "db.example.com", quoted with " or '
+//	hosts.db\.example\.com
+//	                the same key, with a backslash escaping the next character
+//
+// Empty segments, such as those in "a..b", are ignored.
+func parsePath(name string) ([]segment, os.Error) {
+	var path []segment
+	var key bytes.Buffer
+	flush := func() {
+		if key.Len() > 0 {
+			path = append(path, segment{key.String(), false})
+			key.Reset()
+		}
+	}
+	i := 0
+	for i < len(name) {
+		c := name[i]
+		switch {
+		case strings.HasPrefix(name[i:], PropNameDelim):
+			flush()
+			i += len(PropNameDelim)
+		case c == '\\':
+			if i+1 == len(name) {
+				return nil, &NameError{name, i, "escape at end of name"}
+			}
+			key.WriteByte(name[i+1])
+			i += 2
+		case c == '[':
+			flush()
+			seg, n, err := parseSelector(name, i)
+			if err != nil {
+				return nil, err
+			}
+			path = append(path, seg)
+			i = n
+			if i < len(name) && name[i] != '[' && !strings.HasPrefix(name[i:], PropNameDelim) {
+				return nil, &NameError{name, i, "expected '" + PropNameDelim + "' or '[' after ']'"}
+			}
+		case c == ']':
+			return nil, &NameError{name, i, "unexpected ']'"}
+		default:
+			key.WriteByte(c)
+			i++
+		}
+	}
+	flush()
+	return path, nil
+}
+
+// parseSelector parses the bracketed selector starting at name[i] and
+// returns its segment and the offset following the closing bracket.
+func parseSelector(name string, i int) (segment, int, os.Error) {
+	start := i
+	i++
+	if i < len(name) && (name[i] == '"' || name[i] == '\'') {
+		quote := name[i]
+		var key bytes.Buffer
+		for i++; i < len(name) && name[i] != quote; i++ {
+			if name[i] == '\\' {
+				i++
+				if i == len(name) {
+					break
+				}
+			}
+			key.WriteByte(name[i])
+		}
+		if i >= len(name) {
+			return segment{}, 0, &NameError{name, start, "unterminated quoted key"}
+		}
+		i++
+		if i == len(name) || name[i] != ']' {
+			return segment{}, 0, &NameError{name, i, "expected ']' after quoted key"}
+		}
+		return segment{key.String(), false}, i + 1, nil
+	}
+
+	end := strings.Index(name[i:], "]")
+	if end < 0 {
+		return segment{}, 0, &NameError{name, start, "unterminated index"}
+	}
+	s := name[i : i+end]
+	if c := strings.Index(s, ":"); c >= 0 {
+		lo, hi := s[:c], s[c+1:]
+		if !isIndex(lo, true) || !isIndex(hi, true) {
+			return segment{}, 0, &NameError{name, i, "slice bounds are not integers: " + s}
+		}
+		return segment{s, true}, i + end + 1, nil
+	}
+	if !isIndex(s, false) {
+		return segment{}, 0, &NameError{name, i, "index is not an integer: " + s}
+	}
+	n, _ := strconv.Atoi64(s)
+	return segment{strconv.Itoa64(n), true}, i + end + 1, nil
+}
+
+// isIndex reports whether s is an integer with an optional minus sign,
+// or is empty if empty is true.
+func isIndex(s string, empty bool) bool {
+	if s == "" {
+		return empty
+	}
+	if s[0] == '-' {
+		s = s[1:]
+	}
+	if !isDigits(s) {
+		return false
+	}
+	_, err := strconv.Atoi64(s)
+	return err == nil
+}
+
+// quoteKey returns a map key as it is written in a property name,
+// as a quoted selector if it contains special characters.
+func quoteKey(key string) string {
+	if key != "" && strings.Index(key, PropNameDelim) < 0 && strings.IndexAny(key, "[]\\\"'") < 0 {
+		return key
+	}
+	var b bytes.Buffer
+	b.WriteString("[\"")
+	for i := 0; i < len(key); i++ {
+		if key[i] == '"' || key[i] == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(key[i])
+	}
+	b.WriteString("\"]")
+	return b.String()
+}
+
+// arrayIndex returns the index in an array of length n selected by an
+// index segment key, counting from the end of the array if the index
+// is negative. It returns false if the key is not an integer.
+func arrayIndex(key string, n int) (int64, bool) {
+	idx, err := strconv.Atoi64(key)
+	if err != nil {
+		return 0, false
+	}
+	if idx < 0 {
+		idx += int64(n)
+	}
+	return idx, true
+}
+
+// isSlice reports whether a segment selects a slice of an array.
+func isSlice(seg segment) bool {
+	return seg.index && strings.Index(seg.key, ":") >= 0
+}
+
+// sliceBounds returns the bounds in an array of length n selected by
+// a slice segment key, clamped to the array.
+func sliceBounds(key string, n int) (lo, hi int) {
+	c := strings.Index(key, ":")
+	lo, hi = 0, n
+	if c > 0 {
+		lo = clampIndex(key[:c], n)
+	}
+	if c+1 < len(key) {
+		hi = clampIndex(key[c+1:], n)
+	}
+	if lo > hi {
+		lo = hi
+	}
+	return
+}
+
+func clampIndex(s string, n int) int {
+	idx, _ := arrayIndex(s, n)
+	if idx < 0 {
+		return 0
+	}
+	if idx > int64(n) {
+		return n
+	}
+	return int(idx)
+}
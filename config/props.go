@@ -7,41 +7,53 @@ import (
 	"os"
 	"io"
 	"fmt"
-	"json"
-	"regexp"
+	"big"
+	"sync"
 	"strings"
 	"strconv"
 	"reflect"
+	"io/ioutil"
 )
 
 var PropNameDelim = "."
-var PropNameRegex = regexp.MustCompile("^(.+)\\[([0-9]+)\\]$")
 
 type Properties struct {
-	root interface{}
+	root    interface{}
+	path    []segment                       // location of root within the top level properties
+	env     *EnvOverlay                     // environment variables that override property values
+	origins *originTree                     // names of the layers that supplied property values
+	mu      *sync.RWMutex                   // guards root and the file name of a ConfigFile
+	javaDoc []*javaLine                     // lines of the Java properties file read
+	interp  bool                            // expand ${...} references in string values
+	top     *Properties                     // the top level properties, nil if p is the top
+	strict  func(name string, err os.Error) // handles errors hidden by the Default getters
+	coerce  *Coercion                       // conversions of values not of the type requested
+	flags   []*flagValue                    // values set by command line flags, in the order set
 }
 
 // ReadProperties decodes JSON data and stores it in a Properties structure.
+// Numbers are stored as Number values so that integers are not rounded.
 func ReadProperties(r io.Reader) (*Properties, os.Error) {
-	var root interface{}
-	decoder := json.NewDecoder(r)
-	err := decoder.Decode(&root)
+	data, err := ioutil.ReadAll(r)
 	if err != nil {
 		return nil, err
 	}
-	return &Properties{root}, nil
+	root, err := decodeJSON(data)
+	if err != nil {
+		return nil, withText(err, data)
+	}
+	return &Properties{root: root}, nil
 }
 
 // Bool retrieves a boolean property value and an error if not found.
 func (p *Properties) Bool(name ...interface{}) (bool, os.Error) {
-	prop, err := p.Property(name...)
+	prop, err := p.property(name)
 	if err != nil {
 		return false, err
 	}
-	v, ok := prop.(bool)
+	v, ok := p.toBool(prop)
 	if !ok {
-		err = os.NewError("property is not of type 'bool'.")
-		return false, err
+		return false, p.mismatch(name, "bool", prop)
 	}
 	return v, nil
 }
@@ -50,47 +62,109 @@ func (p *Properties) Bool(name ...interface{}) (bool, os.Error) {
 func (p *Properties) BoolDefault(dflt bool, name ...interface{}) bool {
 	v, err := p.Bool(name...)
 	if err != nil {
+		p.defaultError(name, err)
 		return dflt
 	}
 	return v
 }
 
 // Int64 retrieves a int64 property value or an error if not found.
+// An error is also returned if the number is not an integer or
+// is out of range.
 func (p *Properties) Int64(name ...interface{}) (int64, os.Error) {
-	f, err := p.Float64(name...)
+	n, err := p.number(name, "int64")
 	if err != nil {
 		return 0, err
 	}
-	return int64(f), nil
+	return n.Int64()
 }
 
 // Int64Default retrieves a int64 property value or the specified default.
 func (p *Properties) Int64Default(dflt int64, name ...interface{}) int64 {
-	v, err := p.Float64(name...)
+	v, err := p.Int64(name...)
+	if err != nil {
+		p.defaultError(name, err)
+		return dflt
+	}
+	return v
+}
+
+// Int32 retrieves a int32 property value or an error if not found.
+// An error is also returned if the number is not an integer or
+// is out of range.
+func (p *Properties) Int32(name ...interface{}) (int32, os.Error) {
+	n, err := p.number(name, "int32")
+	if err != nil {
+		return 0, err
+	}
+	return n.Int32()
+}
+
+// Int32Default retrieves a int32 property value or the specified default.
+func (p *Properties) Int32Default(dflt int32, name ...interface{}) int32 {
+	v, err := p.Int32(name...)
+	if err != nil {
+		p.defaultError(name, err)
+		return dflt
+	}
+	return v
+}
+
+// Uint64 retrieves a uint64 property value or an error if not found.
+// An error is also returned if the number is not an integer or
+// is out of range.
+func (p *Properties) Uint64(name ...interface{}) (uint64, os.Error) {
+	n, err := p.number(name, "uint64")
+	if err != nil {
+		return 0, err
+	}
+	return n.Uint64()
+}
+
+// Uint64Default retrieves a uint64 property value or the specified default.
+func (p *Properties) Uint64Default(dflt uint64, name ...interface{}) uint64 {
+	v, err := p.Uint64(name...)
 	if err != nil {
+		p.defaultError(name, err)
 		return dflt
 	}
-	return int64(v)
+	return v
+}
+
+// BigInt retrieves a big.Int property value or an error if not found.
+// An error is also returned if the number is not an integer.
+func (p *Properties) BigInt(name ...interface{}) (*big.Int, os.Error) {
+	n, err := p.number(name, "big.Int")
+	if err != nil {
+		return nil, err
+	}
+	return n.BigInt()
+}
+
+// BigIntDefault retrieves a big.Int property value or the specified default.
+func (p *Properties) BigIntDefault(dflt *big.Int, name ...interface{}) *big.Int {
+	v, err := p.BigInt(name...)
+	if err != nil {
+		p.defaultError(name, err)
+		return dflt
+	}
+	return v
 }
 
 // Float64 retrieves a float64 property value or an error if not found.
 func (p *Properties) Float64(name ...interface{}) (float64, os.Error) {
-	prop, err := p.Property(name...)
+	n, err := p.number(name, "float64")
 	if err != nil {
 		return 0.0, err
 	}
-	v, ok := prop.(float64)
-	if !ok {
-		err = os.NewError("property is not of type 'float64'.")
-		return 0.0, err
-	}
-	return v, nil
+	return n.Float64()
 }
 
 // Float64Default retrieves a float64 property value or the specified default.
 func (p *Properties) Float64Default(dflt float64, name ...interface{}) float64 {
 	v, err := p.Float64(name...)
 	if err != nil {
+		p.defaultError(name, err)
 		return dflt
 	}
 	return v
@@ -98,14 +172,13 @@ func (p *Properties) Float64Default(dflt float64, name ...interface{}) float64 {
 
 // String retrieves a string property value or an error if not found.
 func (p *Properties) String(name ...interface{}) (string, os.Error) {
-	prop, err := p.Property(name...)
+	prop, err := p.property(name)
 	if err != nil {
 		return "", err
 	}
-	v, ok := prop.(string)
+	v, ok := p.toString(prop)
 	if !ok {
-		err = os.NewError("property is not of type 'string'.")
-		return "", err
+		return "", p.mismatch(name, "string", prop)
 	}
 	return v, nil
 }
@@ -114,93 +187,251 @@ func (p *Properties) String(name ...interface{}) (string, os.Error) {
 func (p *Properties) StringDefault(dflt string, name ...interface{}) string {
 	v, err := p.String(name...)
 	if err != nil {
+		p.defaultError(name, err)
 		return dflt
 	}
 	return v
 }
 
+// number retrieves a Number property value, or an error naming
+// the type requested if the property is not a number.
+func (p *Properties) number(name []interface{}, typ string) (Number, os.Error) {
+	prop, err := p.property(name)
+	if err != nil {
+		return "", err
+	}
+	v, ok := p.toNumber(prop)
+	if !ok {
+		return "", p.mismatch(name, typ, prop)
+	}
+	return v, nil
+}
+
+// mismatch returns a *TypeMismatchError for a property value
+// that is not of the expected type.
+func (p *Properties) mismatch(name []interface{}, expected string, prop interface{}) os.Error {
+	full := p.fullName(name, nil)
+	return &TypeMismatchError{full, full, expected, typeName(prop)}
+}
+
+// invalid returns a *ValueError for a property value that cannot be
+// converted, the value at below within the property named name.
+func (p *Properties) invalid(name []interface{}, err os.Error, below ...segment) os.Error {
+	return &ValueError{p.fullName(name, below), err}
+}
+
+// elementMismatch returns a *TypeMismatchError for the element at seg
+// of the property named name, which is not of the expected type.
+func (p *Properties) elementMismatch(name []interface{}, seg segment, expected string, prop interface{}) os.Error {
+	full := p.fullName(name, []segment{seg})
+	return &TypeMismatchError{full, full, expected, typeName(prop)}
+}
+
+// fullName returns the full name of the value at below within the
+// property named name.
+func (p *Properties) fullName(name []interface{}, below []segment) string {
+	path, _ := parseName(name...)
+	return formatPath(joinPath(joinPath(p.path, path), below))
+}
+
 // Properties retrieves a Properties value or an error if not found.
+// A name ending in a slice, such as "servers[1:3]", retrieves an array
+// of the elements selected. The array is a copy, so changes to it are
+// not seen by p, but the maps and arrays it contains are shared.
 func (p *Properties) Properties(name ...interface{}) (*Properties, os.Error) {
-	prop, err := p.Property(name...)
+	path, err := parseName(name...)
+	if err != nil {
+		return nil, err
+	}
+	prop, err := p.lookup(path)
 	if err != nil {
 		return nil, err
 	}
-	return &Properties{prop}, nil
+	if e, ok := prop.(envValue); ok {
+		prop = string(e)
+	}
+	p.rlock()
+	defer p.runlock()
+	return p.sub(prop, path), nil
 }
 
 // Property retrieves a raw Property value and an error if not found. 
 func (p *Properties) Property(name ...interface{}) (interface{}, os.Error) {
-	sname, err := coerce(name...)
+	prop, err := p.property(name)
+	if e, ok := prop.(envValue); ok {
+		return string(e), nil
+	}
+	return prop, err
+}
+
+// property retrieves a raw property value like Property, except
+// that values from the environment overlay, and strings expanded by
+// interpolation, are of type envValue.
+func (p *Properties) property(name []interface{}) (interface{}, os.Error) {
+	path, err := parseName(name...)
 	if err != nil {
 		return nil, err
 	}
+	prop, err := p.lookup(path)
+	if err != nil || !p.interp {
+		return prop, err
+	}
+	return p.interpolate(prop, path)
+}
 
-	if len(sname) == 1 {
-		sname = split(sname[0])
+// lookup retrieves the property value at path, first consulting
+// the environment overlay if one is set.
+func (p *Properties) lookup(path []segment) (interface{}, os.Error) {
+	p.rlock()
+	defer p.runlock()
+	if v, ok := p.envLookup(joinPath(p.path, path)); ok {
+		return v, nil
 	}
+	return lookup(p.root, p.path, path)
+}
 
-	var cur interface{} = p.root
-	for _, sn := range sname {
+// sub creates Properties for the value found at path, with the
+// same options as the receiver. Negative indices in path are resolved,
+// so the sub properties keep the elements they were created for. It is
+// called with the lock held.
+func (p *Properties) sub(prop interface{}, path []segment) *Properties {
+	resolved, _ := resolveIndices(p.root, path)
+	q := *p
+	q.root = prop
+	q.path = joinPath(p.path, resolved)
+	q.top = p.topLevel()
+	return &q
+}
+
+// segment is a single step along a property path. The index flag
+// records that the step was written as an array index, either with
+// the "name[n]" syntax or as an integer name argument. An index key
+// of the form "lo:hi" selects a slice of the array.
+type segment struct {
+	key   string
+	index bool
+}
+
+// parseName coerces the name arguments into a path of segments.
+// A single name is parsed by parsePath, multiple names are not.
+func parseName(name ...interface{}) ([]segment, os.Error) {
+	path, err := coerce(name...)
+	if err != nil {
+		return nil, err
+	}
+	if len(path) == 1 && !path[0].index {
+		return parsePath(path[0].key)
+	}
+	return path, nil
+}
+
+// joinPath returns a new path containing the segments of both paths.
+func joinPath(base, path []segment) []segment {
+	p := make([]segment, 0, len(base)+len(path))
+	p = append(p, base...)
+	return append(p, path...)
+}
+
+// formatPath joins path segments into a property name of the form
+// "a.b[2].c", quoting keys that contain special characters.
+func formatPath(path []segment) string {
+	var name string
+	for _, seg := range path {
+		key := quoteKey(seg.key)
+		if seg.index {
+			name += "[" + seg.key + "]"
+		} else if len(name) > 0 && key[0] != '[' {
+			name += PropNameDelim + key
+		} else {
+			name += key
+		}
+	}
+	return name
+}
+
+// lookup walks the property tree from root along the specified path.
+// The root is at base within the top level properties, which is used
+// to name the property in the error returned if it is not found.
+func lookup(root interface{}, base, path []segment) (interface{}, os.Error) {
+	cur := root
+	for i, seg := range path {
+		sn := seg.key
 		switch v := cur.(type) {
 		case map[string]interface{}:
+			if isSlice(seg) {
+				full := joinPath(base, path)
+				return nil, &TypeMismatchError{formatPath(full), formatPath(full[:len(base)+i]), "array", "map"}
+			}
 			var ok bool
 			cur, ok = v[sn]
 			if !ok {
-				err := os.NewError(fmt.Sprint("map property does not contain key:", sn))
-				return nil, err
+				full := joinPath(base, path)
+				return nil, &NotFoundError{formatPath(full), formatPath(full[:len(base)+i+1])}
 			}
 		case []interface{}:
-			idx, err := strconv.Atoi64(sn)
-			if err != nil {
-				return nil, err
+			if isSlice(seg) {
+				lo, hi := sliceBounds(sn, len(v))
+				a := make([]interface{}, hi-lo)
+				copy(a, v[lo:hi])
+				cur = a
+				continue
+			}
+			full := joinPath(base, path)
+			idx, ok := arrayIndex(sn, len(v))
+			if !ok {
+				return nil, &TypeMismatchError{formatPath(full), formatPath(full[:len(base)+i]), "map", "array"}
 			}
 			if (idx < 0) || (idx >= int64(len(v))) {
-				err = os.NewError(fmt.Sprint("array property does not contain index:", idx))
-				return nil, err
+				idx, _ = strconv.Atoi64(sn)
+				return nil, &IndexOutOfRangeError{formatPath(full), formatPath(full[:len(base)+i+1]), idx, len(v)}
 			}
 			cur = v[idx]
 		default:
-			err = os.NewError(fmt.Sprint("property is not container, cannot get property:", sn))
-			return nil, err
+			expected := "map"
+			if seg.index {
+				expected = "array"
+			}
+			full := joinPath(base, path)
+			return nil, &TypeMismatchError{formatPath(full), formatPath(full[:len(base)+i]), expected, typeName(cur)}
 		}
 	}
 	return cur, nil
 }
 
-func split(name string) []string {
-	var sname []string
-	names := strings.Split(name, PropNameDelim)
-	for _, n := range names {
+// split splits a key read from a file format without a property name
+// syntax of its own, such as an INI file, into path segments. A key
+// that is not a valid property name is split on PropNameDelim only.
+func split(name string) []segment {
+	if path, err := parsePath(name); err == nil {
+		return path
+	}
+	var path []segment
+	for _, n := range strings.Split(name, PropNameDelim) {
 		if len(n) > 0 {
-			match := PropNameRegex.FindStringSubmatch(n)
-			if match == nil {
-				sname = append(sname, n)
-			} else {
-				sname = append(sname, match[1], match[2])
-			}
+			path = append(path, segment{n, false})
 		}
 	}
-	return sname
+	return path
 }
 
-func coerce(name ...interface{}) (sname []string, err os.Error) {
+func coerce(name ...interface{}) (path []segment, err os.Error) {
 L:
 	for _, n := range name {
 		switch v := n.(type) {
 		case string:
-			sname = append(sname, v)
+			path = append(path, segment{v, false})
 		case func() string:
-			sname = append(sname, v())
+			path = append(path, segment{v(), false})
 		case fmt.Stringer:
-			sname = append(sname, v.String())
+			path = append(path, segment{v.String(), false})
 		case int:
-			sname = append(sname, strconv.Itoa(v))
+			path = append(path, segment{strconv.Itoa(v), true})
 		case int64:
-			sname = append(sname, strconv.Itoa64(v))
+			path = append(path, segment{strconv.Itoa64(v), true})
 		case float32:
-			sname = append(sname, strconv.Itoa64(int64(v)))
+			path = append(path, segment{strconv.Itoa64(int64(v)), true})
 		case float64:
-			sname = append(sname, strconv.Itoa64(int64(v)))
+			path = append(path, segment{strconv.Itoa64(int64(v)), true})
 		default:
 			err = os.NewError(fmt.Sprint("name cannot be coerced from type: ", reflect.TypeOf(n)))
 			break L
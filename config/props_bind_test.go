@@ -0,0 +1,164 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config_test
+
+import (
+	"config"
+	"strings"
+	"testing"
+)
+
+var TestBindConfigData = `{
+	"server":{
+		"host":"localhost",
+		"port":8080,
+		"ratio":0.5,
+		"tls":{ "enabled":true },
+		"aliases":[ "one", "two" ],
+		"limits":{ "read":10, "write":20 }
+	},
+	"bad":{
+		"host":80,
+		"port":1.5,
+		"aliases":[ "one", 2 ]
+	}
+}`
+
+type TestBindTLS struct {
+	Enabled bool   `config:"enabled"`
+	Cert    string `config:"cert,default=server.pem"`
+}
+
+type TestBindServer struct {
+	Host    string             `config:"host,required"`
+	Port    uint16             `config:"port"`
+	Ratio   float64            `config:"ratio"`
+	TLS     *TestBindTLS       `config:"tls"`
+	Aliases []string           `config:"aliases"`
+	Limits  map[string]int     `config:"limits"`
+	Backlog int                `config:"backlog,default=128"`
+	Methods []string           `config:"methods,default=GET,POST"`
+	Ignored string             `config:"-"`
+	Raw     *config.Properties `config:"tls"`
+}
+
+type TestBindApp struct {
+	Name   string         `config:"name"`
+	Server TestBindServer `config:"server"`
+}
+
+func TestBind(t *testing.T) {
+
+	t.Log("Read the following JSON config data:\n" + TestBindConfigData)
+
+	properties, err := config.ReadProperties(strings.NewReader(TestBindConfigData))
+	if err == nil {
+		t.Log("Success reading config properties.")
+	} else {
+		t.Fatal("Error reading config properties:", err)
+	}
+
+	var s TestBindServer
+	err = properties.Bind(&s, "server")
+	if err == nil {
+		t.Log("Success binding property 'server'.")
+	} else {
+		t.Fatal("Error binding property 'server':", err)
+	}
+
+	if s.Host == "localhost" && s.Port == 8080 && s.Ratio == 0.5 {
+		t.Log("Bound scalar fields are 'localhost', 8080 and 0.5.")
+	} else {
+		t.Error("Bound scalar fields are not 'localhost', 8080 and 0.5:", s.Host, s.Port, s.Ratio)
+	}
+	if s.TLS != nil && s.TLS.Enabled && s.TLS.Cert == "server.pem" {
+		t.Log("Bound nested struct field is enabled with default cert.")
+	} else {
+		t.Error("Bound nested struct field is not enabled with default cert.")
+	}
+	if len(s.Aliases) == 2 && s.Aliases[1] == "two" {
+		t.Log("Bound slice field is [ one two ].")
+	} else {
+		t.Error("Bound slice field is not [ one two ]:", s.Aliases)
+	}
+	if s.Limits["read"] == 10 && s.Limits["write"] == 20 {
+		t.Log("Bound map field is { read:10 write:20 }.")
+	} else {
+		t.Error("Bound map field is not { read:10 write:20 }:", s.Limits)
+	}
+	if s.Backlog == 128 && len(s.Methods) == 2 && s.Methods[1] == "POST" {
+		t.Log("Default values for 'backlog' and 'methods' are bound.")
+	} else {
+		t.Error("Default values for 'backlog' and 'methods' are not bound:", s.Backlog, s.Methods)
+	}
+	if b, _ := s.Raw.Bool("enabled"); b {
+		t.Log("Bound Properties field contains 'enabled'.")
+	} else {
+		t.Error("Bound Properties field does not contain 'enabled'.")
+	}
+
+	var bad TestBindServer
+	err = properties.Bind(&bad, "bad")
+	if berr, ok := err.(*config.BindError); ok && len(berr.Errors) == 3 {
+		t.Log("Binding property 'bad' returns all errors:", err)
+	} else {
+		t.Error("Binding property 'bad' does not return all errors:", err)
+	}
+
+	sub, err := properties.Properties("bad")
+	if err != nil {
+		t.Fatal("Error getting properties 'bad':", err)
+	}
+	err = sub.Bind(&bad)
+	if berr, ok := err.(*config.BindError); ok && len(berr.Errors) == 3 && berr.Errors[0].Name == "bad.host" {
+		t.Log("Binding properties 'bad' names the errors by the full property name:", err)
+	} else {
+		t.Error("Binding properties 'bad' does not name the errors by the full property name:", err)
+	}
+
+	var missing TestBindServer
+	err = properties.Bind(&missing, "server.tls")
+	if berr, ok := err.(*config.BindError); ok && berr.Errors[0].Name == "server.tls.host" {
+		t.Log("Binding property 'server.tls' reports missing 'server.tls.host'.")
+	} else {
+		t.Error("Binding property 'server.tls' does not report missing 'server.tls.host':", err)
+	}
+
+	var app TestBindApp
+	err = properties.Bind(&app, "server.tls")
+	if berr, ok := err.(*config.BindError); ok && len(berr.Errors) == 1 && berr.Errors[0].Name == "server.tls.server.host" {
+		t.Log("Binding a missing nested struct reports missing 'server.tls.server.host'.")
+	} else {
+		t.Error("Binding a missing nested struct does not report missing 'server.tls.server.host':", err)
+	}
+}
+
+var TestBindInterpConfigData = `{
+	"base":{ "host":"example.com", "port":9000 },
+	"server":{ "host":"${base.host}", "port":"${base.port}", "aliases":[ "www.${base.host}" ] }
+}`
+
+func TestBindInterp(t *testing.T) {
+
+	t.Log("Read the following JSON config data:\n" + TestBindInterpConfigData)
+
+	properties, err := config.ReadProperties(strings.NewReader(TestBindInterpConfigData))
+	if err == nil {
+		t.Log("Success reading config properties.")
+	} else {
+		t.Fatal("Error reading config properties:", err)
+	}
+	properties.SetInterpolation(true)
+
+	var s TestBindServer
+	err = properties.Bind(&s, "server")
+	if err != nil {
+		t.Fatal("Error binding property 'server':", err)
+	}
+	if s.Host == "example.com" && s.Port == 9000 && len(s.Aliases) == 1 && s.Aliases[0] == "www.example.com" {
+		t.Log("Bound fields have references expanded:", s.Host, s.Port, s.Aliases)
+	} else {
+		t.Error("Bound fields do not have references expanded:", s.Host, s.Port, s.Aliases)
+	}
+}
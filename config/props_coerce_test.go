@@ -0,0 +1,115 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config_test
+
+import (
+	"config"
+	"strings"
+	"testing"
+)
+
+var TestCoerceConfigData = `{
+	"server":{
+		"port":"8080",
+		"ratio":" 0.75 ",
+		"debug":"Yes",
+		"tls":"off",
+		"verbose":1,
+		"version":2.5,
+		"enabled":true,
+		"mode":"fast",
+		"ports":[ "80", 443 ]
+	}
+}`
+
+func TestCoerce(t *testing.T) {
+
+	t.Log("Read the following JSON config data:\n" + TestCoerceConfigData)
+
+	properties, err := config.ReadProperties(strings.NewReader(TestCoerceConfigData))
+	if err == nil {
+		t.Log("Success reading config properties.")
+	} else {
+		t.Fatal("Error reading config properties:", err)
+	}
+
+	if _, err = properties.Int64("server.port"); err != nil {
+		t.Log("Int64 value for 'server.port' is not coerced by default:", err)
+	} else {
+		t.Error("Int64 value for 'server.port' is coerced by default.")
+	}
+
+	properties.SetCoercion(&config.Coercion{})
+	server, err := properties.Properties("server")
+	if err != nil {
+		t.Fatal("Error getting properties 'server':", err)
+	}
+
+	if port, err := server.Int64("port"); err == nil && port == 8080 {
+		t.Log("Int64 value for 'server.port' is coerced:", port)
+	} else {
+		t.Error("Int64 value for 'server.port' is not coerced:", port, err)
+	}
+	if ratio, err := server.Float64("ratio"); err == nil && ratio == 0.75 {
+		t.Log("Float64 value for 'server.ratio' is coerced:", ratio)
+	} else {
+		t.Error("Float64 value for 'server.ratio' is not coerced:", ratio, err)
+	}
+
+	bools := []struct {
+		name  string
+		value bool
+	}{
+		{"debug", true},
+		{"tls", false},
+		{"verbose", true},
+		{"enabled", true},
+	}
+	for _, b := range bools {
+		if v, err := server.Bool(b.name); err == nil && v == b.value {
+			t.Log("Bool value for 'server."+b.name+"' is coerced:", v)
+		} else {
+			t.Error("Bool value for 'server."+b.name+"' is not coerced:", v, err)
+		}
+	}
+	if _, err = server.Bool("mode"); err != nil {
+		t.Log("Bool value for 'server.mode' is not coerced:", err)
+	} else {
+		t.Error("Bool value for 'server.mode' is coerced.")
+	}
+
+	if version, err := server.String("version"); err == nil && version == "2.5" {
+		t.Log("String value for 'server.version' is coerced:", version)
+	} else {
+		t.Error("String value for 'server.version' is not coerced:", version, err)
+	}
+	if enabled, err := server.String("enabled"); err == nil && enabled == "true" {
+		t.Log("String value for 'server.enabled' is coerced:", enabled)
+	} else {
+		t.Error("String value for 'server.enabled' is not coerced:", enabled, err)
+	}
+
+	ports, err := server.Int64s("ports")
+	if err == nil && len(ports) == 2 && ports[0] == 80 && ports[1] == 443 {
+		t.Log("Int64s value for 'server.ports' is coerced:", ports)
+	} else {
+		t.Error("Int64s value for 'server.ports' is not coerced:", ports, err)
+	}
+
+	properties.SetCoercion(&config.Coercion{
+		Bool: func(v interface{}) (bool, bool) {
+			if s, ok := v.(string); ok && s == "fast" {
+				return true, true
+			}
+			return config.CoerceBool(v)
+		},
+	})
+	mode, err := properties.Bool("server.mode")
+	debug, derr := properties.Bool("server.debug")
+	if err == nil && mode && derr == nil && debug {
+		t.Log("Bool value for 'server.mode' is coerced by a custom converter.")
+	} else {
+		t.Error("Bool value for 'server.mode' is not coerced by a custom converter:", err, derr)
+	}
+}
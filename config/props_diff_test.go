@@ -0,0 +1,127 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config_test
+
+import (
+	"fmt"
+	"time"
+	"bytes"
+	"config"
+	"strings"
+	"testing"
+)
+
+var TestDiffConfigDataA = `{
+	"server":{ "host":"localhost", "port":8080, "ratio":1 },
+	"aliases":[ "www", "web" ],
+	"db":{ "url":"postgres://db" }
+}`
+
+var TestDiffConfigDataB = `{
+	"server":{ "host":"example.com", "port":8080, "ratio":1.0, "tls":{ "cert":"a/b~c" } },
+	"aliases":[ "web", "www", "api" ]
+}`
+
+func TestDiff(t *testing.T) {
+
+	t.Log("Read the following JSON config data:\n" + TestDiffConfigDataA + "\n" + TestDiffConfigDataB)
+
+	a, err := config.ReadProperties(strings.NewReader(TestDiffConfigDataA))
+	if err != nil {
+		t.Fatal("Error reading config properties:", err)
+	}
+	b, err := config.ReadProperties(strings.NewReader(TestDiffConfigDataB))
+	if err == nil {
+		t.Log("Success reading config properties.")
+	} else {
+		t.Fatal("Error reading config properties:", err)
+	}
+
+	diffs := []struct {
+		opts   *config.DiffOptions
+		result string
+	}{
+		{nil, "aliases[0]:modified aliases[1]:modified aliases[2]:added db.url:removed " +
+			"server.host:modified server.ratio:modified server.tls.cert:added"},
+		{&config.DiffOptions{NumericEqual: true}, "aliases[0]:modified aliases[1]:modified aliases[2]:added db.url:removed " +
+			"server.host:modified server.tls.cert:added"},
+		{&config.DiffOptions{IgnoreOrder: true, NumericEqual: true}, "aliases[2]:added db.url:removed " +
+			"server.host:modified server.tls.cert:added"},
+	}
+	for _, d := range diffs {
+		changes := config.Diff(a, b, d.opts)
+		result := make([]string, len(changes))
+		for i, c := range changes {
+			result[i] = fmt.Sprint(c.Name, ":", c.Kind)
+		}
+		if s := strings.Join(result, " "); s == d.result {
+			t.Log("Diff with options", d.opts, "is", s)
+		} else {
+			t.Error("Diff with options", d.opts, "is not", d.result, ":", s)
+		}
+	}
+
+	var buf bytes.Buffer
+	err = config.WriteDiff(&buf, config.Diff(a, b, &config.DiffOptions{IgnoreOrder: true, NumericEqual: true}))
+	expected := `+ aliases[2]: "api"
+- db.url: "postgres://db"
+- server.host: "localhost"
++ server.host: "example.com"
++ server.tls.cert: "a/b~c"
+`
+	if err == nil && buf.String() == expected {
+		t.Log("Diff text is:\n" + buf.String())
+	} else {
+		t.Error("Diff text is not:\n"+expected, "\n:\n"+buf.String(), err)
+	}
+
+	buf.Reset()
+	err = config.WritePatch(&buf, a, b, &config.DiffOptions{NumericEqual: true})
+	patch := strings.Join(strings.Fields(buf.String()), " ")
+	expected = `[ { "op": "replace", "path": "/aliases/0", "value": "web" }, ` +
+		`{ "op": "replace", "path": "/aliases/1", "value": "www" }, ` +
+		`{ "op": "add", "path": "/aliases/2", "value": "api" }, ` +
+		`{ "op": "remove", "path": "/db" }, ` +
+		`{ "op": "replace", "path": "/server/host", "value": "example.com" }, ` +
+		`{ "op": "add", "path": "/server/tls", "value": { "cert": "a/b~c" } } ]`
+	if err == nil && patch == expected {
+		t.Log("JSON Patch is:\n" + buf.String())
+	} else {
+		t.Error("JSON Patch is not:\n"+expected, "\n:\n"+patch, err)
+	}
+}
+
+func TestDiffConcurrent(t *testing.T) {
+
+	a, err := config.ReadConfigFile(TestFileName)
+	if err != nil {
+		t.Fatal("Error reading test config file:", err)
+	}
+	b, err := config.ReadConfigFile(TestFileName)
+	if err != nil {
+		t.Fatal("Error reading test config file:", err)
+	}
+
+	done := make(chan bool)
+	run := func(fn func(i int)) {
+		go func() {
+			for i := 0; i < 200; i++ {
+				fn(i)
+			}
+			done <- true
+		}()
+	}
+	run(func(i int) { config.Diff(a.Properties, b.Properties, nil) })
+	run(func(i int) { config.Diff(b.Properties, a.Properties, nil) })
+	run(func(i int) { a.Set(i, "counter") })
+	run(func(i int) { b.Set(i, "counter") })
+	for i := 0; i < 4; i++ {
+		select {
+		case <-done:
+		case <-time.After(10e9):
+			t.Fatal("Timeout diffing properties in both orders while setting them.")
+		}
+	}
+	t.Log("Diffing properties in both orders while setting them does not deadlock.")
+}
@@ -0,0 +1,103 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config_test
+
+import (
+	"os"
+	"config"
+	"strings"
+	"testing"
+)
+
+var TestEnvConfigData = `{
+	"server":{
+		"host":"localhost",
+		"port":8080,
+		"debug":false
+	},
+	"name":"test"
+}`
+
+func TestEnv(t *testing.T) {
+
+	t.Log("Read the following JSON config data:\n" + TestEnvConfigData)
+
+	properties, err := config.ReadProperties(strings.NewReader(TestEnvConfigData))
+	if err == nil {
+		t.Log("Success reading config properties.")
+	} else {
+		t.Fatal("Error reading config properties:", err)
+	}
+
+	os.Setenv("TESTENV_SERVER_PORT", "9090")
+	os.Setenv("TESTENV_SERVER_DEBUG", "true")
+	os.Setenv("TESTENV_SERVER_TIMEOUT", "2.5")
+	properties.SetEnvOverlay(&config.EnvOverlay{Prefix: "TESTENV_"})
+
+	if i, _ := properties.Int64("server.port"); i == 9090 {
+		t.Log("Int64 value for 'server.port' is 9090 from environment.")
+	} else {
+		t.Error("Int64 value for 'server.port' is not 9090 from environment.")
+	}
+	if b, _ := properties.Bool("server", "debug"); b == true {
+		t.Log("Bool value for 'server.debug' is true from environment.")
+	} else {
+		t.Error("Bool value for 'server.debug' is not true from environment.")
+	}
+	if f, _ := properties.Float64("server.timeout"); f == 2.5 {
+		t.Log("Float64 value for 'server.timeout' is 2.5 from environment.")
+	} else {
+		t.Error("Float64 value for 'server.timeout' is not 2.5 from environment.")
+	}
+	if s, _ := properties.String("server.host"); s == "localhost" {
+		t.Log("String value for 'server.host' is 'localhost'.")
+	} else {
+		t.Error("String value for 'server.host' is not 'localhost'.")
+	}
+
+	server, err := properties.Properties("server")
+	if err != nil {
+		t.Fatal("Error getting Properties value from property 'server':", err)
+	}
+	if s, _ := server.String("port"); s == "9090" {
+		t.Log("String value for Properties('server').String('port') is '9090' from environment.")
+	} else {
+		t.Error("String value for Properties('server').String('port') is not '9090' from environment.")
+	}
+
+	overrides := properties.EnvOverrides()
+	if strings.Join(overrides, " ") == "server.debug server.port server.timeout" {
+		t.Log("Overridden properties are:", overrides)
+	} else {
+		t.Error("Overridden properties are not [ server.debug server.port server.timeout ]:", overrides)
+	}
+	if overrides = server.EnvOverrides(); strings.Join(overrides, " ") == "server.debug server.port server.timeout" {
+		t.Log("Overridden properties of 'server' are:", overrides)
+	} else {
+		t.Error("Overridden properties of 'server' are not [ server.debug server.port server.timeout ]:", overrides)
+	}
+
+	var bound struct {
+		Host    string  `config:"host"`
+		Port    int     `config:"port"`
+		Timeout float64 `config:"timeout"`
+	}
+	err = properties.Bind(&bound, "server")
+	if err == nil && bound.Host == "localhost" && bound.Port == 9090 && bound.Timeout == 2.5 {
+		t.Log("Bind uses the values from environment.")
+	} else {
+		t.Error("Bind does not use the values from environment:", bound, err)
+	}
+
+	properties.SetEnvOverlay(&config.EnvOverlay{
+		Prefix:    "TESTENV_",
+		Transform: func(name []string) string { return strings.Join(name, "__") },
+	})
+	os.Setenv("TESTENV_server__host", "example.com")
+	if s, _ := properties.String("server.host"); s == "example.com" {
+		t.Log("String value for 'server.host' is 'example.com' with custom transform.")
+	} else {
+		t.Error("String value for 'server.host' is not 'example.com' with custom transform.")
+	}
+}
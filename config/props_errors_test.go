@@ -0,0 +1,77 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config_test
+
+import (
+	"config"
+	"strings"
+	"testing"
+)
+
+var TestErrorsConfigData = `{
+	"server":{
+		"host":"localhost",
+		"port":"8080",
+		"aliases":[ "www", "web" ]
+	}
+}`
+
+func TestErrors(t *testing.T) {
+
+	t.Log("Read the following JSON config data:\n" + TestErrorsConfigData)
+
+	properties, err := config.ReadProperties(strings.NewReader(TestErrorsConfigData))
+	if err == nil {
+		t.Log("Success reading config properties.")
+	} else {
+		t.Fatal("Error reading config properties:", err)
+	}
+
+	_, err = properties.String("server.tls.cert")
+	if e, ok := err.(*config.NotFoundError); ok && e.Name == "server.tls.cert" && e.Segment == "server.tls" {
+		t.Log("NotFoundError for 'server.tls.cert':", err)
+	} else {
+		t.Error("Error for 'server.tls.cert' is not a NotFoundError for 'server.tls':", err)
+	}
+	if config.IsNotFound(err) {
+		t.Log("IsNotFound is true for 'server.tls.cert'.")
+	} else {
+		t.Error("IsNotFound is false for 'server.tls.cert'.")
+	}
+
+	_, err = properties.String("server.aliases[5]")
+	if e, ok := err.(*config.IndexOutOfRangeError); ok && e.Index == 5 && e.Len == 2 {
+		t.Log("IndexOutOfRangeError for 'server.aliases[5]':", err)
+	} else {
+		t.Error("Error for 'server.aliases[5]' is not an IndexOutOfRangeError:", err)
+	}
+	if config.IsNotFound(err) {
+		t.Log("IsNotFound is true for 'server.aliases[5]'.")
+	} else {
+		t.Error("IsNotFound is false for 'server.aliases[5]'.")
+	}
+
+	server, err := properties.Properties("server")
+	if err != nil {
+		t.Fatal("Error getting properties 'server':", err)
+	}
+	_, err = server.Int64("port")
+	if e, ok := err.(*config.TypeMismatchError); ok && e.Name == "server.port" && e.Expected == "int64" && e.Actual == "string" {
+		t.Log("TypeMismatchError for 'server.port':", err)
+	} else {
+		t.Error("Error for 'server.port' is not a TypeMismatchError from string to int64:", err)
+	}
+	if !config.IsNotFound(err) {
+		t.Log("IsNotFound is false for 'server.port'.")
+	} else {
+		t.Error("IsNotFound is true for 'server.port'.")
+	}
+
+	_, err = properties.String("server.host.name")
+	if e, ok := err.(*config.TypeMismatchError); ok && e.Segment == "server.host" && e.Expected == "map" {
+		t.Log("TypeMismatchError for 'server.host.name':", err)
+	} else {
+		t.Error("Error for 'server.host.name' is not a TypeMismatchError for 'server.host':", err)
+	}
+}
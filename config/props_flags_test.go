@@ -0,0 +1,174 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config_test
+
+import (
+	"os"
+	"flag"
+	"config"
+	"strings"
+	"testing"
+	"io/ioutil"
+	"path/filepath"
+)
+
+var TestFlagsConfigData = `{
+	"server":{
+		"host":"localhost",
+		"port":8080,
+		"debug":false,
+		"aliases":[ "www" ]
+	},
+	"log":{ "level":"info" }
+}`
+
+func TestFlags(t *testing.T) {
+
+	t.Log("Read the following JSON config data:\n" + TestFlagsConfigData)
+
+	properties, err := config.ReadProperties(strings.NewReader(TestFlagsConfigData))
+	if err == nil {
+		t.Log("Success reading config properties.")
+	} else {
+		t.Fatal("Error reading config properties:", err)
+	}
+
+	defer restoreEnv(os.Environ())
+	os.Setenv("TESTFLAGS_SERVER_PORT", "7070")
+	os.Setenv("TESTFLAGS_SERVER_HOST", "env.example.com")
+	properties.SetEnvOverlay(&config.EnvOverlay{Prefix: "TESTFLAGS_"})
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	err = properties.Flags(fs, "server.port", "server.debug", "server.aliases")
+	if err != nil {
+		t.Fatal("Error registering flags:", err)
+	}
+	properties.OverrideFlag(fs, "set")
+	if f := fs.Lookup("server.port"); f != nil && f.DefValue == "7070" {
+		t.Log("Flag 'server.port' has the current value as default:", f.DefValue)
+	} else {
+		t.Error("Flag 'server.port' does not have the current value as default:", f)
+	}
+	if err = properties.Flags(fs, "server.missing"); err != nil {
+		t.Log("Flags reports a missing property:", err)
+	} else {
+		t.Error("Flags does not report a missing property.")
+	}
+
+	args := []string{
+		"-server.port=9090",
+		"-server.debug=true",
+		`-server.aliases=["web","api"]`,
+		"-set", "log.level=debug",
+		"-set", "log.max=10",
+		"-set", "log.json=true",
+		"-set", `log.tags=["a","b"]`,
+		"-set", "log.file=/var/log/app.log",
+	}
+	err = fs.Parse(args)
+	if err == nil {
+		t.Log("Success parsing flags:", args)
+	} else {
+		t.Fatal("Error parsing flags:", err)
+	}
+
+	port, err := properties.Int64("server.port")
+	if err == nil && port == 9090 {
+		t.Log("Int64 value for 'server.port' is 9090 from the flag, not the environment.")
+	} else {
+		t.Error("Int64 value for 'server.port' is not 9090 from the flag:", port, err)
+	}
+	if host, _ := properties.String("server.host"); host == "env.example.com" {
+		t.Log("String value for 'server.host' is from the environment.")
+	} else {
+		t.Error("String value for 'server.host' is not from the environment:", host)
+	}
+	if debug, _ := properties.Bool("server.debug"); debug {
+		t.Log("Bool value for 'server.debug' is true from the flag.")
+	} else {
+		t.Error("Bool value for 'server.debug' is not true from the flag.")
+	}
+	if aliases, _ := properties.Strings("server.aliases"); strings.Join(aliases, ",") == "web,api" {
+		t.Log("Strings value for 'server.aliases' is from the flag:", aliases)
+	} else {
+		t.Error("Strings value for 'server.aliases' is not from the flag:", aliases)
+	}
+
+	level, _ := properties.String("log.level")
+	max, _ := properties.Int64("log.max")
+	json, _ := properties.Bool("log.json")
+	tags, _ := properties.Strings("log.tags")
+	file, _ := properties.String("log.file")
+	if level == "debug" && max == 10 && json && strings.Join(tags, ",") == "a,b" && file == "/var/log/app.log" {
+		t.Log("Override flag values are set with inferred types.")
+	} else {
+		t.Error("Override flag values are not set with inferred types:", level, max, json, tags, file)
+	}
+
+	for _, arg := range []string{"-server.port=abc", "-set=nodelimiter"} {
+		if err = fs.Parse([]string{arg}); err != nil {
+			t.Log("Flag '"+arg+"' is not valid:", err)
+		} else {
+			t.Error("Flag '" + arg + "' is valid.")
+		}
+	}
+}
+
+func TestFlagsReload(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal("Error creating temp directory:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	fname := filepath.Join(dir, "config.json")
+	err = ioutil.WriteFile(fname, []byte(TestFlagsConfigData), 0644)
+	if err != nil {
+		t.Fatal("Error writing test config file:", err)
+	}
+	c, err := config.ReadConfigFile(fname)
+	if err != nil {
+		t.Fatal("Error reading test config file:", err)
+	}
+
+	defer restoreEnv(os.Environ())
+	os.Setenv("TESTFLAGSRELOAD_SERVER_PORT", "7070")
+	c.SetEnvOverlay(&config.EnvOverlay{Prefix: "TESTFLAGSRELOAD_"})
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	err = c.Flags(fs, "server.port")
+	if err != nil {
+		t.Fatal("Error registering flags:", err)
+	}
+	c.OverrideFlag(fs, "set")
+	err = fs.Parse([]string{"-server.port=9090", "-set", "log.level=debug"})
+	if err != nil {
+		t.Fatal("Error parsing flags:", err)
+	}
+
+	data := strings.Replace(TestFlagsConfigData, "localhost", "example.com", 1)
+	err = ioutil.WriteFile(fname, []byte(data), 0644)
+	if err != nil {
+		t.Fatal("Error writing test config file:", err)
+	}
+	changes, err := c.Reload()
+	if err != nil {
+		t.Fatal("Error reloading test config file:", err)
+	}
+	if len(changes) == 1 && changes[0].Name == "server.host" {
+		t.Log("Reload changes only the property changed in the file.")
+	} else {
+		t.Error("Reload changes properties other than 'server.host':", changes)
+	}
+
+	port, _ := c.Int64("server.port")
+	level, _ := c.String("log.level")
+	host, _ := c.String("server.host")
+	if port == 9090 && level == "debug" && host == "example.com" {
+		t.Log("Values set by flags are kept after reloading the config file.")
+	} else {
+		t.Error("Values set by flags are not kept after reloading the config file:", port, level, host)
+	}
+}
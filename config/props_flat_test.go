@@ -0,0 +1,137 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config_test
+
+import (
+	"fmt"
+	"config"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var TestFlatConfigData = `{
+	"server":{
+		"host":"localhost",
+		"port":8080,
+		"debug":false,
+		"proxy":null,
+		"aliases":[ "www", { "name":"web" }, [ 1, 2 ] ],
+		"tls":{},
+		"routes":[],
+		"db.example.com":"postgres"
+	}
+}`
+
+func TestFlat(t *testing.T) {
+
+	t.Log("Read the following JSON config data:\n" + TestFlatConfigData)
+
+	properties, err := config.ReadProperties(strings.NewReader(TestFlatConfigData))
+	if err == nil {
+		t.Log("Success reading config properties.")
+	} else {
+		t.Fatal("Error reading config properties:", err)
+	}
+
+	flat, err := properties.Flatten()
+	if err != nil {
+		t.Fatal("Error flattening config properties:", err)
+	}
+	strs, err := properties.FlattenStrings()
+	if err != nil {
+		t.Fatal("Error flattening config properties to strings:", err)
+	}
+	values := []struct {
+		name, value string
+	}{
+		{"server.host", "localhost"},
+		{"server.port", "8080"},
+		{"server.debug", "false"},
+		{"server.proxy", ""},
+		{"server.aliases[0]", "www"},
+		{"server.aliases[1].name", "web"},
+		{"server.aliases[2][0]", "1"},
+		{"server.aliases[2][1]", "2"},
+		{"server.tls", "{}"},
+		{"server.routes", "[]"},
+		{`server["db.example.com"]`, "postgres"},
+	}
+	for _, v := range values {
+		if s, ok := strs[v.name]; ok && s == v.value {
+			t.Log("Flat value for '"+v.name+"' is", s)
+		} else {
+			t.Error("Flat value for '"+v.name+"' is not", v.value, ":", s)
+		}
+	}
+	if len(flat) == len(values) && len(strs) == len(values) {
+		t.Log("Flat maps have an entry for each leaf.")
+	} else {
+		t.Error("Flat maps do not have an entry for each leaf:", flat, strs)
+	}
+
+	rebuilt, err := config.FromFlat(flat)
+	if err != nil {
+		t.Fatal("Error rebuilding config properties:", err)
+	}
+	orig, _ := properties.Property()
+	copy, _ := rebuilt.Property()
+	if reflect.DeepEqual(orig, copy) {
+		t.Log("FromFlat rebuilds the flattened properties.")
+	} else {
+		t.Error("FromFlat does not rebuild the flattened properties:", orig, copy)
+	}
+
+	rebuilt, err = config.FromFlatStrings(strs)
+	if err != nil {
+		t.Fatal("Error rebuilding config properties from strings:", err)
+	}
+	copy, _ = rebuilt.Property()
+	if reflect.DeepEqual(orig, copy) {
+		t.Log("FromFlatStrings rebuilds the properties flattened to strings.")
+	} else {
+		t.Error("FromFlatStrings does not rebuild the properties flattened to strings:", orig, copy)
+	}
+
+	sparse, err := config.FromFlat(map[string]interface{}{"list[2]": 3, "list[0]": "a"})
+	if err == nil {
+		list, _ := sparse.Property("list")
+		if a, ok := list.([]interface{}); ok && len(a) == 3 && a[0] == "a" && a[1] == nil {
+			t.Log("FromFlat fills missing array elements with null:", list)
+		} else {
+			t.Error("FromFlat does not fill missing array elements with null:", list)
+		}
+	} else {
+		t.Error("Error rebuilding sparse array:", err)
+	}
+
+	_, err = config.FromFlat(map[string]interface{}{"a": 1, "a.b": 2})
+	if err != nil {
+		t.Log("FromFlat reports a name continuing past a value:", err)
+	} else {
+		t.Error("FromFlat does not report a name continuing past a value.")
+	}
+
+	dense := make(map[string]interface{})
+	for i := 0; i < 1200; i++ {
+		dense[fmt.Sprintf("list[%d]", i)] = i
+	}
+	rebuilt, err = config.FromFlat(dense)
+	if err == nil {
+		if i, _ := rebuilt.Int64("list[1199]"); i == 1199 {
+			t.Log("FromFlat rebuilds an array with more elements than the gap limit.")
+		} else {
+			t.Error("FromFlat does not rebuild element 'list[1199]':", i)
+		}
+	} else {
+		t.Error("Error rebuilding large array:", err)
+	}
+
+	_, err = config.FromFlat(map[string]interface{}{"list[2000000000]": 1})
+	if _, ok := err.(*config.IndexOutOfRangeError); ok {
+		t.Log("FromFlat rejects an index far past the end of an array:", err)
+	} else {
+		t.Error("FromFlat does not reject an index far past the end of an array:", err)
+	}
+}
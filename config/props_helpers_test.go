@@ -0,0 +1,20 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config_test
+
+import (
+	"os"
+	"strings"
+)
+
+// restoreEnv replaces the environment with the variables of env, as
+// returned by os.Environ, so tests can defer undoing their changes.
+func restoreEnv(env []string) {
+	os.Clearenv()
+	for _, kv := range env {
+		if i := strings.Index(kv, "="); i > 0 {
+			os.Setenv(kv[:i], kv[i+1:])
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config_test
+
+import (
+	"config"
+	"strings"
+	"testing"
+)
+
+var TestINIConfigData = `; test config
+name = test
+
+[server]
+host = localhost
+port: 8080 ; http
+debug = true # for now
+motd = "  Hello World  "
+url = http://localhost/#top
+
+[server.tls]
+cert = server.pem
+
+# sections may be repeated
+[server]
+timeout = 2.5
+`
+
+func TestINI(t *testing.T) {
+
+	t.Log("Read the following INI config data:\n" + TestINIConfigData)
+
+	properties, err := config.ReadINI(strings.NewReader(TestINIConfigData))
+	if err == nil {
+		t.Log("Success reading config properties.")
+	} else {
+		t.Fatal("Error reading config properties:", err)
+	}
+
+	strs := map[string]string{
+		"name":            "test",
+		"server.host":     "localhost",
+		"server.motd":     "  Hello World  ",
+		"server.tls.cert": "server.pem",
+		"server.url":      "http://localhost/#top",
+	}
+	for name, expected := range strs {
+		if s, err := properties.String(name); s == expected {
+			t.Logf("String value for '%s' is %q.", name, expected)
+		} else {
+			t.Errorf("String value for '%s' is not %q: %q %v", name, expected, s, err)
+		}
+	}
+
+	if i, _ := properties.Int64("server.port"); i == 8080 {
+		t.Log("Int64 value for 'server.port' is 8080.")
+	} else {
+		t.Error("Int64 value for 'server.port' is not 8080.")
+	}
+	if b, _ := properties.Bool("server.debug"); b == true {
+		t.Log("Bool value for 'server.debug' is true.")
+	} else {
+		t.Error("Bool value for 'server.debug' is not true.")
+	}
+	if f, _ := properties.Float64("server.timeout"); f == 2.5 {
+		t.Log("Float64 value for 'server.timeout' from repeated section is 2.5.")
+	} else {
+		t.Error("Float64 value for 'server.timeout' from repeated section is not 2.5.")
+	}
+
+	_, err = config.ReadINI(strings.NewReader("[server\nhost = localhost\n"))
+	if err != nil {
+		t.Log("Reading INI with bad section returns error:", err)
+	} else {
+		t.Error("Reading INI with bad section does not return error.")
+	}
+}
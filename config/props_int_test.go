@@ -53,37 +53,25 @@ func TestInt(t *testing.T) {
 		t.Error("Error getting int64 value from property 'int2':", err)
 	}
 
-	i, err = properties.Int64("level2.int3")
-	if err == nil {
-		if i == 0 {
-			t.Log("Int64 value for 'int3' is 0.")
-		} else {
-			t.Error("Int64 value for 'int3' is not 0.")
-		}
+	_, err = properties.Int64("level2.int3")
+	if err != nil {
+		t.Log("Int64 value for 'int3' is not an integer:", err)
 	} else {
-		t.Error("Error getting int64 value from property 'int1':", err)
+		t.Error("Int64 value for 'int3' did not return an error.")
 	}
 
-	i, err = properties.Int64("level2.int4[0]")
-	if err == nil {
-		if i == 1 {
-			t.Log("Int64 value for 'int4[0]' is 1.")
-		} else {
-			t.Error("Int64 value for 'int4[0]' is not 1.")
-		}
+	_, err = properties.Int64("level2.int4[0]")
+	if err != nil {
+		t.Log("Int64 value for 'int4[0]' is not an integer:", err)
 	} else {
-		t.Error("Error getting int64 value from property 'int4[0]':", err)
+		t.Error("Int64 value for 'int4[0]' did not return an error.")
 	}
 
-	i, err = properties.Int64("level2", "int4", 1)
-	if err == nil {
-		if i == 2 {
-			t.Log("Int64 value for 'int4[1]' is 2.")
-		} else {
-			t.Error("Int64 value for 'int4[1]' is not 2.")
-		}
+	i = properties.Int64Default(99, "level2", "int4", 1)
+	if i == 99 {
+		t.Log("Int64 value for 'int4[1]' is 99.")
 	} else {
-		t.Error("Error getting int64 value from property 'int4[1]':", err)
+		t.Error("Int64 value for 'int4[1]' is not 99.")
 	}
 
 	i = properties.Int64Default(99, "level2", "int5")
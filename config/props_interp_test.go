@@ -0,0 +1,117 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config_test
+
+import (
+	"os"
+	"config"
+	"strings"
+	"testing"
+	"io/ioutil"
+	"path/filepath"
+)
+
+var TestInterpConfigData = `{
+	"base":{ "dir":"/srv/app", "port":8000 },
+	"logs":"${base.dir}/logs",
+	"cache":"${env:TESTINTERP_HOME}/cache",
+	"secret":"${file:SECRET_FILE}",
+	"port":"${base.port}",
+	"mode":"${run.mode:-dev}",
+	"nested":"${missing:-${base.dir}}",
+	"literal":"$${base.dir}",
+	"loop1":"${loop2}",
+	"loop2":"${loop1}",
+	"hosts":[ "${base.dir}", "other" ]
+}`
+
+func TestInterp(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "config_interp")
+	if err != nil {
+		t.Fatal("Error creating temporary directory:", err)
+	}
+	defer os.RemoveAll(dir)
+	secret := filepath.Join(dir, "secret.txt")
+	err = ioutil.WriteFile(secret, []byte("s3cret\n"), 0600)
+	if err != nil {
+		t.Fatal("Error writing secret file:", err)
+	}
+	data := strings.Replace(TestInterpConfigData, "SECRET_FILE", secret, 1)
+
+	t.Log("Read the following JSON config data:\n" + data)
+
+	properties, err := config.ReadProperties(strings.NewReader(data))
+	if err == nil {
+		t.Log("Success reading config properties.")
+	} else {
+		t.Fatal("Error reading config properties:", err)
+	}
+
+	if s, _ := properties.String("logs"); s == "${base.dir}/logs" {
+		t.Log("String value for 'logs' is not expanded by default.")
+	} else {
+		t.Error("String value for 'logs' is expanded by default:", s)
+	}
+
+	os.Setenv("TESTINTERP_HOME", "/home/test")
+	properties.SetInterpolation(true)
+
+	expanded := []struct {
+		name, value string
+	}{
+		{"logs", "/srv/app/logs"},
+		{"cache", "/home/test/cache"},
+		{"secret", "s3cret"},
+		{"mode", "dev"},
+		{"nested", "/srv/app"},
+		{"literal", "${base.dir}"},
+	}
+	for _, e := range expanded {
+		s, err := properties.String(e.name)
+		if err == nil && s == e.value {
+			t.Log("String value for '" + e.name + "' is '" + s + "'.")
+		} else {
+			t.Error("String value for '"+e.name+"' is not '"+e.value+"':", s, err)
+		}
+	}
+
+	if i, err := properties.Int64("port"); err == nil && i == 8000 {
+		t.Log("Int64 value for 'port' is 8000.")
+	} else {
+		t.Error("Int64 value for 'port' is not 8000:", i, err)
+	}
+
+	if s, _ := properties.RawString("logs"); s == "${base.dir}/logs" {
+		t.Log("RawString value for 'logs' is '${base.dir}/logs'.")
+	} else {
+		t.Error("RawString value for 'logs' is not '${base.dir}/logs':", s)
+	}
+
+	_, err = properties.String("loop1")
+	if err != nil && strings.Index(err.String(), "cycle") >= 0 {
+		t.Log("String value for 'loop1' reports a cycle:", err)
+	} else {
+		t.Error("String value for 'loop1' did not report a cycle:", err)
+	}
+
+	if h, err := properties.Strings("hosts"); err == nil && h[0] == "/srv/app" {
+		t.Log("Strings value for 'hosts' is", h)
+	} else {
+		t.Error("Strings value for 'hosts' is not expanded:", h, err)
+	}
+
+	base, err := properties.Properties("base")
+	if err != nil {
+		t.Fatal("Error getting properties 'base':", err)
+	}
+	if err = base.Set("${base.port}", "url"); err != nil {
+		t.Fatal("Error setting property 'base.url':", err)
+	}
+	if s, _ := base.String("url"); s == "8000" {
+		t.Log("String value for 'base.url' is '8000'.")
+	} else {
+		t.Error("String value for 'base.url' is not '8000':", s)
+	}
+}
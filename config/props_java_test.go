@@ -0,0 +1,99 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config_test
+
+import (
+	"bytes"
+	"config"
+	"strings"
+	"testing"
+)
+
+var TestJavaConfigData = `# Server settings
+server.host = localhost
+server.port: 8080
+server.motd=Hello \
+            World
+! paths
+path.home=C:\\Users\\test
+path.unicode=caf\u00e9
+path.emoji=\uD83D\uDE00
+key\ with\ spaces=value
+list[0]=one
+list[1]=two
+`
+
+var TestJavaConfigOutput = `# Server settings
+server.host = example.com
+server.port: 8080
+server.motd=Hello \
+            World
+! paths
+path.unicode=caf\u00e9
+path.emoji=\uD83D\uDE00
+key\ with\ spaces=value
+list[0]=one
+list[1]=two
+server.debug=true
+`
+
+func TestJava(t *testing.T) {
+
+	t.Log("Read the following Java properties config data:\n" + TestJavaConfigData)
+
+	properties, err := config.ReadJavaProperties(strings.NewReader(TestJavaConfigData))
+	if err == nil {
+		t.Log("Success reading config properties.")
+	} else {
+		t.Fatal("Error reading config properties:", err)
+	}
+
+	strs := map[string]string{
+		"server.host":     "localhost",
+		"server.port":     "8080",
+		"server.motd":     "Hello World",
+		"path.home":       `C:\Users\test`,
+		"path.unicode":    "café",
+		"key with spaces": "value",
+		`["list[1]"]`:     "two",
+		"path.emoji":      "\U0001F600",
+	}
+	for name, expected := range strs {
+		if s, err := properties.String(name); s == expected {
+			t.Logf("String value for '%s' is %q.", name, expected)
+		} else {
+			t.Errorf("String value for '%s' is not %q: %q %v", name, expected, s, err)
+		}
+	}
+
+	properties.Set("example.com", "server.host")
+	properties.Set(true, "server.debug")
+	properties.Delete("path.home")
+
+	var buf bytes.Buffer
+	err = config.WriteJavaProperties(&buf, properties)
+	if err != nil {
+		t.Fatal("Error writing config properties:", err)
+	}
+	if buf.String() == TestJavaConfigOutput {
+		t.Log("Written Java properties keep comments and key order.")
+	} else {
+		t.Error("Written Java properties do not match expected output:\n" + buf.String())
+	}
+
+	for _, data := range []string{"log=INFO\nlog.file=out.log\n", "log.file=out.log\nlog=INFO\n"} {
+		properties, err = config.ReadJavaProperties(strings.NewReader(data))
+		if err != nil {
+			t.Error("Error reading Java properties with conflicting keys:", err)
+			continue
+		}
+		log, _ := properties.String("log")
+		file, _ := properties.String(`["log.file"]`)
+		if log == "INFO" && file == "out.log" {
+			t.Logf("Java properties %q keep the conflicting keys in the same map.", data)
+		} else {
+			t.Errorf("Java properties %q do not keep the conflicting keys in the same map: %q %q", data, log, file)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config_test
+
+import (
+	"os"
+	"fmt"
+	"config"
+	"strings"
+	"testing"
+)
+
+var TestKeysConfigData = `{
+	"server":{
+		"host":"localhost",
+		"port":8080,
+		"debug":false,
+		"proxy":null,
+		"aliases":[ "www", "web" ],
+		"tls":{}
+	},
+	"db.example.com":"postgres"
+}`
+
+func TestKeys(t *testing.T) {
+
+	t.Log("Read the following JSON config data:\n" + TestKeysConfigData)
+
+	properties, err := config.ReadProperties(strings.NewReader(TestKeysConfigData))
+	if err == nil {
+		t.Log("Success reading config properties.")
+	} else {
+		t.Fatal("Error reading config properties:", err)
+	}
+
+	keys, err := properties.Keys("server")
+	if err == nil && strings.Join(keys, ",") == "aliases,debug,host,port,proxy,tls" {
+		t.Log("Keys of 'server' are", keys)
+	} else {
+		t.Error("Keys of 'server' are not sorted:", keys, err)
+	}
+	if _, err = properties.Keys("server.aliases"); err != nil {
+		t.Log("Keys of 'server.aliases' is an error:", err)
+	} else {
+		t.Error("Keys of 'server.aliases' is not an error.")
+	}
+
+	lens := []struct {
+		name string
+		len  int
+	}{
+		{"", 2},
+		{"server", 6},
+		{"server.aliases", 2},
+		{"server.tls", 0},
+	}
+	for _, l := range lens {
+		if n, err := properties.Len(l.name); err == nil && n == l.len {
+			t.Log("Len of '"+l.name+"' is", n)
+		} else {
+			t.Error("Len of '"+l.name+"' is not", l.len, ":", n, err)
+		}
+	}
+	if _, err = properties.Len("server.host"); err != nil {
+		t.Log("Len of 'server.host' is an error:", err)
+	} else {
+		t.Error("Len of 'server.host' is not an error.")
+	}
+
+	kinds := []struct {
+		name string
+		kind config.Kind
+	}{
+		{"server", config.MapKind},
+		{"server.aliases", config.ArrayKind},
+		{"server.host", config.StringKind},
+		{"server.port", config.NumberKind},
+		{"server.debug", config.BoolKind},
+		{"server.proxy", config.NullKind},
+	}
+	for _, k := range kinds {
+		if kind, err := properties.Kind(k.name); err == nil && kind == k.kind {
+			t.Log("Kind of '"+k.name+"' is", kind)
+		} else {
+			t.Error("Kind of '"+k.name+"' is not", k.kind, ":", kind, err)
+		}
+	}
+	if _, err = properties.Kind("server.missing"); config.IsNotFound(err) {
+		t.Log("Kind of 'server.missing' is not found:", err)
+	} else {
+		t.Error("Kind of 'server.missing' is not a not found error:", err)
+	}
+
+	var leaves []string
+	err = properties.Walk(func(name string, value interface{}) os.Error {
+		leaves = append(leaves, fmt.Sprint(name, "=", value))
+		return nil
+	})
+	expected := `["db.example.com"]=postgres server.aliases[0]=www server.aliases[1]=web server.debug=false ` +
+		`server.host=localhost server.port=8080 server.proxy=<nil> server.tls=map[]`
+	if s := strings.Join(leaves, " "); err == nil && s == expected {
+		t.Log("Walk visits the leaves:", s)
+	} else {
+		t.Error("Walk does not visit the leaves:", s, err)
+	}
+
+	for _, leaf := range []string{`["db.example.com"]`, "server.aliases[1]"} {
+		if _, err := properties.Property(leaf); err == nil {
+			t.Log("Walk name '" + leaf + "' is a property name.")
+		} else {
+			t.Error("Walk name '"+leaf+"' is not a property name:", err)
+		}
+	}
+
+	defer restoreEnv(os.Environ())
+	os.Setenv("TESTKEYS_SERVER", "override")
+	os.Setenv("TESTKEYS_SERVER_ALIASES", "a,b,c")
+	properties.SetEnvOverlay(&config.EnvOverlay{Prefix: "TESTKEYS_"})
+	keys, err = properties.Keys("server")
+	n, lerr := properties.Len("server.aliases")
+	if err == nil && len(keys) == 6 && lerr == nil && n == 2 {
+		t.Log("Keys and Len are not replaced by variables named like a map or array.")
+	} else {
+		t.Error("Keys and Len are replaced by variables named like a map or array:", keys, err, n, lerr)
+	}
+	properties.SetEnvOverlay(nil)
+
+	stop := os.NewError("stop")
+	count := 0
+	err = properties.Walk(func(name string, value interface{}) os.Error {
+		count++
+		return stop
+	})
+	if err == stop && count == 1 {
+		t.Log("Walk stops at the first error.")
+	} else {
+		t.Error("Walk does not stop at the first error:", count, err)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config_test
+
+import (
+	"config"
+	"strings"
+	"testing"
+)
+
+var TestLayerDefaultsData = `{
+	"server":{ "host":"localhost", "port":8080 },
+	"hosts":[ "a", "b" ]
+}`
+
+var TestLayerSiteData = `{
+	"server":{ "port":9090 },
+	"hosts":[ "c" ]
+}`
+
+func TestLayer(t *testing.T) {
+
+	defaults, err := config.ReaderLayer("defaults", strings.NewReader(TestLayerDefaultsData))
+	if err != nil {
+		t.Fatal("Error reading defaults layer:", err)
+	}
+	site, err := config.ReaderLayer("site", strings.NewReader(TestLayerSiteData))
+	if err != nil {
+		t.Fatal("Error reading site layer:", err)
+	}
+	local, err := config.MapLayer("local", map[string]interface{}{
+		"server": map[string]interface{}{"debug": true},
+	})
+	if err != nil {
+		t.Fatal("Error creating local layer:", err)
+	}
+
+	properties := config.NewLayered(false, defaults, site, local)
+
+	if s, _ := properties.String("server.host"); s == "localhost" {
+		t.Log("String value for 'server.host' is 'localhost'.")
+	} else {
+		t.Error("String value for 'server.host' is not 'localhost'.")
+	}
+	if i, _ := properties.Int64("server.port"); i == 9090 {
+		t.Log("Int64 value for 'server.port' is 9090.")
+	} else {
+		t.Error("Int64 value for 'server.port' is not 9090.")
+	}
+	if b, _ := properties.Bool("server.debug"); b == true {
+		t.Log("Bool value for 'server.debug' is true.")
+	} else {
+		t.Error("Bool value for 'server.debug' is not true.")
+	}
+	if s, _ := properties.String("hosts[0]"); s == "c" {
+		t.Log("String value for replaced array 'hosts[0]' is 'c'.")
+	} else {
+		t.Error("String value for replaced array 'hosts[0]' is not 'c'.")
+	}
+
+	origins := map[string]string{
+		"server.host":  "defaults",
+		"server.port":  "site",
+		"server.debug": "local",
+		"hosts[0]":     "site",
+	}
+	for name, layer := range origins {
+		if origin, _ := properties.Origin(name); origin == layer {
+			t.Log("Origin of property '" + name + "' is '" + layer + "'.")
+		} else {
+			t.Error("Origin of property '"+name+"' is not '"+layer+"':", origin)
+		}
+	}
+
+	server, _ := properties.Properties("server")
+	if origin, _ := server.Origin("port"); origin == "site" {
+		t.Log("Origin of Properties('server').Origin('port') is 'site'.")
+	} else {
+		t.Error("Origin of Properties('server').Origin('port') is not 'site'.")
+	}
+
+	properties.Set(1, "server.port")
+	if origin, _ := properties.Origin("server.port"); origin == "" {
+		t.Log("Origin of property 'server.port' is '' after set.")
+	} else {
+		t.Error("Origin of property 'server.port' is not '' after set.")
+	}
+
+	appended := config.NewLayered(true, defaults, site)
+	if s, _ := appended.String("hosts[2]"); s == "c" {
+		t.Log("String value for appended array 'hosts[2]' is 'c'.")
+	} else {
+		t.Error("String value for appended array 'hosts[2]' is not 'c'.")
+	}
+	if origin, _ := appended.Origin("hosts[0]"); origin == "defaults" {
+		t.Log("Origin of appended array 'hosts[0]' is 'defaults'.")
+	} else {
+		t.Error("Origin of appended array 'hosts[0]' is not 'defaults'.")
+	}
+	if origin, _ := appended.Origin("hosts[2]"); origin == "site" {
+		t.Log("Origin of appended array 'hosts[2]' is 'site'.")
+	} else {
+		t.Error("Origin of appended array 'hosts[2]' is not 'site'.")
+	}
+
+	err = appended.Delete("hosts[0]")
+	if err != nil {
+		t.Fatal("Error deleting property 'hosts[0]':", err)
+	}
+	first, _ := appended.Origin("hosts[0]")
+	last, _ := appended.Origin("hosts[-1]")
+	if first == "defaults" && last == "site" {
+		t.Log("Origins of 'hosts' elements are shifted after delete.")
+	} else {
+		t.Error("Origins of 'hosts' elements are not shifted after delete:", first, last)
+	}
+	appended.Set("d", "hosts[-1]")
+	first, _ = appended.Origin("hosts[0]")
+	last, _ = appended.Origin("hosts[1]")
+	if first == "defaults" && last == "" {
+		t.Log("Origin of 'hosts[-1]' is '' after set.")
+	} else {
+		t.Error("Origin of 'hosts[-1]' is not '' after set:", first, last)
+	}
+}
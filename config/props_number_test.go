@@ -0,0 +1,117 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config_test
+
+import (
+	"big"
+	"bytes"
+	"config"
+	"strings"
+	"testing"
+)
+
+var TestNumberConfigData = `{
+	"big":9007199254740993,
+	"max":18446744073709551615,
+	"neg":-2147483649,
+	"exp":1.5e3,
+	"frac":3.7,
+	"huge":123456789012345678901234567890
+}`
+
+func TestNumber(t *testing.T) {
+
+	t.Log("Read the following JSON config data:\n" + TestNumberConfigData)
+
+	properties, err := config.ReadProperties(strings.NewReader(TestNumberConfigData))
+	if err == nil {
+		t.Log("Success reading config properties.")
+	} else {
+		t.Fatal("Error reading config properties:", err)
+	}
+
+	i, err := properties.Int64("big")
+	if err == nil {
+		if i == 9007199254740993 {
+			t.Log("Int64 value for 'big' is 9007199254740993.")
+		} else {
+			t.Error("Int64 value for 'big' is not 9007199254740993:", i)
+		}
+	} else {
+		t.Error("Error getting int64 value from property 'big':", err)
+	}
+
+	u, err := properties.Uint64("max")
+	if err == nil {
+		if u == 18446744073709551615 {
+			t.Log("Uint64 value for 'max' is 18446744073709551615.")
+		} else {
+			t.Error("Uint64 value for 'max' is not 18446744073709551615:", u)
+		}
+	} else {
+		t.Error("Error getting uint64 value from property 'max':", err)
+	}
+
+	_, err = properties.Int64("max")
+	if err != nil {
+		t.Log("Int64 value for 'max' is out of range:", err)
+	} else {
+		t.Error("Int64 value for 'max' did not return an error.")
+	}
+
+	_, err = properties.Uint64("neg")
+	if err != nil {
+		t.Log("Uint64 value for 'neg' is out of range:", err)
+	} else {
+		t.Error("Uint64 value for 'neg' did not return an error.")
+	}
+
+	i32 := properties.Int32Default(7, "neg")
+	if i32 == 7 {
+		t.Log("Int32 value for 'neg' is 7.")
+	} else {
+		t.Error("Int32 value for 'neg' is not 7:", i32)
+	}
+
+	i32, err = properties.Int32("exp")
+	if err == nil {
+		if i32 == 1500 {
+			t.Log("Int32 value for 'exp' is 1500.")
+		} else {
+			t.Error("Int32 value for 'exp' is not 1500:", i32)
+		}
+	} else {
+		t.Error("Error getting int32 value from property 'exp':", err)
+	}
+
+	_, err = properties.Int32("frac")
+	if err != nil && strings.Index(err.String(), "not an integer") >= 0 {
+		t.Log("Int32 value for 'frac' is not an integer:", err)
+	} else {
+		t.Error("Int32 value for 'frac' does not report a number that is not an integer:", err)
+	}
+
+	b, err := properties.BigInt("huge")
+	if err == nil {
+		want, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+		if b.Cmp(want) == 0 {
+			t.Log("BigInt value for 'huge' is 123456789012345678901234567890.")
+		} else {
+			t.Error("BigInt value for 'huge' is not 123456789012345678901234567890:", b)
+		}
+	} else {
+		t.Error("Error getting big.Int value from property 'huge':", err)
+	}
+
+	var buf bytes.Buffer
+	err = config.WriteProperties(&buf, properties)
+	if err != nil {
+		t.Fatal("Error writing config properties:", err)
+	}
+	if strings.Index(buf.String(), "9007199254740993") >= 0 {
+		t.Log("Written value for 'big' is 9007199254740993.")
+	} else {
+		t.Error("Written value for 'big' is not 9007199254740993:\n" + buf.String())
+	}
+}
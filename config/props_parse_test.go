@@ -0,0 +1,66 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config_test
+
+import (
+	"os"
+	"config"
+	"strings"
+	"testing"
+	"io/ioutil"
+	"path/filepath"
+)
+
+var TestParseErrors = []struct {
+	data         string
+	line, column int
+	hint         string
+}{
+	{"{\n\t\"host\":\"localhost\",\n\t\"port\":8080,\n}", 4, 1, "trailing commas"},
+	{"{\n\thost:\"localhost\"\n}", 2, 2, "double quotes"},
+	{"{\n\t\"hosts\":[ \"a\", \"b\", ]\n}", 2, 22, "trailing commas"},
+	{"{\n\t\"a\":1\n\t\"b\":2\n}", 3, 2, "comma may be missing"},
+	{"{\n\t\"a\":'x'\n}", 2, 6, "double quotes"},
+	{"{\n\t\"a\":[ 1, 2\n", 3, 1, "closing brace"},
+}
+
+func TestParseError(t *testing.T) {
+
+	for _, test := range TestParseErrors {
+		_, err := config.ReadProperties(strings.NewReader(test.data))
+		e, ok := err.(*config.ParseError)
+		if !ok {
+			t.Error("Error reading invalid JSON is not a ParseError:", err)
+			continue
+		}
+		if e.Line == test.line && e.Column == test.column && strings.Index(e.Hint, test.hint) >= 0 {
+			t.Log("Error reading invalid JSON:\n" + e.String())
+		} else {
+			t.Errorf("Error reading invalid JSON is not at line %d, column %d with hint '%s':\n%s",
+				test.line, test.column, test.hint, e)
+		}
+	}
+
+	_, err := config.ReadProperties(strings.NewReader("{\n\t\"a\":1,}"))
+	if e, ok := err.(*config.ParseError); ok && e.Excerpt() == "\t\t\"a\":1,}\n\t\t      ^" {
+		t.Log("Excerpt of error is:\n" + e.Excerpt())
+	} else {
+		t.Error("Excerpt of error is not correct:", err)
+	}
+
+	dir, err := ioutil.TempDir("", "config_parse")
+	if err != nil {
+		t.Fatal("Error creating temporary directory:", err)
+	}
+	defer os.RemoveAll(dir)
+	fname := filepath.Join(dir, "bad.yaml")
+	ioutil.WriteFile(fname, []byte("server:\n  host: localhost\n\tport: 8080\n"), 0644)
+
+	_, err = config.ReadConfigFile(fname)
+	if e, ok := err.(*config.ParseError); ok && e.File == fname && e.Line == 3 && e.Text == "\tport: 8080" {
+		t.Log("Error reading invalid YAML file:\n" + e.String())
+	} else {
+		t.Error("Error reading invalid YAML file does not name the file and line:", err)
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config_test
+
+import (
+	"fmt"
+	"bytes"
+	"config"
+	"strings"
+	"testing"
+)
+
+var TestPatchConfigData = `{
+	"server":{ "host":"localhost", "port":8080, "aliases":[ "www", "web" ] },
+	"log":{ "level":"info", "file":"/var/log/app.log" },
+	"paths":{ "a/b":1 }
+}`
+
+var TestPatchData = `[
+	{ "op":"test", "path":"/server/port", "value":8080.0 },
+	{ "op":"replace", "path":"/server/port", "value":9090 },
+	{ "op":"add", "path":"/server/aliases/1", "value":"api" },
+	{ "op":"add", "path":"/server/aliases/-", "value":"app" },
+	{ "op":"remove", "path":"/server/aliases/0" },
+	{ "op":"copy", "from":"/log/level", "path":"/server/level" },
+	{ "op":"move", "from":"/log/file", "path":"/server/log" },
+	{ "op":"replace", "path":"/paths/a~1b", "value":2 }
+]`
+
+func TestPatch(t *testing.T) {
+
+	t.Log("Read the following JSON config data:\n" + TestPatchConfigData)
+
+	properties, err := config.ReadProperties(strings.NewReader(TestPatchConfigData))
+	if err == nil {
+		t.Log("Success reading config properties.")
+	} else {
+		t.Fatal("Error reading config properties:", err)
+	}
+
+	err = properties.ApplyPatch(strings.NewReader(TestPatchData))
+	if err == nil {
+		t.Log("Success applying JSON Patch:\n" + TestPatchData)
+	} else {
+		t.Fatal("Error applying JSON Patch:", err)
+	}
+	values := []struct {
+		name, value string
+	}{
+		{"server.port", "9090"},
+		{"server.aliases", "api,web,app"},
+		{"server.level", "info"},
+		{"log.level", "info"},
+		{"server.log", "/var/log/app.log"},
+		{`paths["a/b"]`, "2"},
+	}
+	for _, v := range values {
+		prop, _ := properties.Property(v.name)
+		s := fmt.Sprint(prop)
+		if a, ok := prop.([]interface{}); ok {
+			s = strings.Trim(strings.Replace(fmt.Sprint(a), " ", ",", -1), "[]")
+		}
+		if s == v.value {
+			t.Log("Value for '"+v.name+"' is", s)
+		} else {
+			t.Error("Value for '"+v.name+"' is not", v.value, ":", s)
+		}
+	}
+	if _, err = properties.Property("log.file"); config.IsNotFound(err) {
+		t.Log("Moved property 'log.file' is removed.")
+	} else {
+		t.Error("Moved property 'log.file' is not removed:", err)
+	}
+
+	failing := `[
+		{ "op":"replace", "path":"/server/port", "value":1 },
+		{ "op":"remove", "path":"/server/aliases/5" }
+	]`
+	err = properties.ApplyPatch(strings.NewReader(failing))
+	if e, ok := err.(*config.PatchError); ok && e.Index == 1 && e.Name == "server.aliases[5]" {
+		t.Log("PatchError reports the failing operation:", err)
+	} else {
+		t.Error("Error is not a PatchError for operation 1:", err)
+	}
+	if port, _ := properties.Int64("server.port"); port == 9090 {
+		t.Log("Failed JSON Patch does not change the properties.")
+	} else {
+		t.Error("Failed JSON Patch changes the properties:", port)
+	}
+
+	err = properties.ApplyPatch(strings.NewReader(`[ { "op":"test", "path":"/log/level", "value":"debug" } ]`))
+	if e, ok := err.(*config.PatchError); ok && e.Index == 0 && e.Op == "test" {
+		t.Log("PatchError reports the failed test:", err)
+	} else {
+		t.Error("Error is not a PatchError for the failed test:", err)
+	}
+
+	merge := `{ "server":{ "host":null, "tls":{ "cert":"c.pem" } }, "log":"off" }`
+	err = properties.ApplyMergePatch(strings.NewReader(merge))
+	if err == nil {
+		t.Log("Success applying JSON Merge Patch:\n" + merge)
+	} else {
+		t.Fatal("Error applying JSON Merge Patch:", err)
+	}
+	_, herr := properties.Property("server.host")
+	cert, _ := properties.String("server.tls.cert")
+	log, _ := properties.String("log")
+	port, _ := properties.Int64("server.port")
+	if config.IsNotFound(herr) && cert == "c.pem" && log == "off" && port == 9090 {
+		t.Log("JSON Merge Patch is applied.")
+	} else {
+		t.Error("JSON Merge Patch is not applied:", herr, cert, log, port)
+	}
+
+	server, err := properties.Properties("server")
+	if err != nil {
+		t.Fatal("Error getting properties 'server':", err)
+	}
+	patch := `[ { "op":"replace", "path":"/port", "value":7070 }, { "op":"add", "path":"/aliases/-", "value":"ftp" } ]`
+	err = server.ApplyPatch(strings.NewReader(patch))
+	if err != nil {
+		t.Fatal("Error applying JSON Patch to properties 'server':", err)
+	}
+	port, _ = properties.Int64("server.port")
+	alias, _ := properties.String("server.aliases[-1]")
+	if port == 7070 && alias == "ftp" {
+		t.Log("JSON Patch applied to properties 'server' is seen from the top level.")
+	} else {
+		t.Error("JSON Patch applied to properties 'server' is not seen from the top level:", port, alias)
+	}
+
+	aliases, err := properties.Properties("server.aliases")
+	if err != nil {
+		t.Fatal("Error getting properties 'server.aliases':", err)
+	}
+	err = aliases.Set("mail", 4)
+	if err != nil {
+		t.Fatal("Error setting element 4 of properties 'server.aliases':", err)
+	}
+	if s, _ := properties.String("server.aliases[4]"); s == "mail" {
+		t.Log("Element set past the end of properties 'server.aliases' is seen from the top level.")
+	} else {
+		t.Error("Element set past the end of properties 'server.aliases' is not seen from the top level:", s)
+	}
+
+	err = properties.Set("example.com", "server.host")
+	if err == nil {
+		err = server.Set(true, "debug")
+	}
+	if err != nil {
+		t.Fatal("Error setting properties 'server.host' and 'debug' of 'server':", err)
+	}
+	host, _ := properties.String("server.host")
+	debug, _ := properties.Bool("server.debug")
+	if host == "example.com" && debug {
+		t.Log("Values set through the top level and through 'server' are both kept.")
+	} else {
+		t.Error("Values set through the top level and through 'server' are not both kept:", host, debug)
+	}
+
+	last, err := properties.Properties("server.aliases[-1]")
+	if err != nil {
+		t.Fatal("Error getting properties 'server.aliases[-1]':", err)
+	}
+	err = properties.Set("smtp", "server.aliases[5]")
+	if err == nil {
+		err = last.Set("imap")
+	}
+	if err != nil {
+		t.Fatal("Error setting elements of 'server.aliases':", err)
+	}
+	mail, _ := properties.String("server.aliases[4]")
+	smtp, _ := properties.String("server.aliases[5]")
+	if mail == "imap" && smtp == "smtp" {
+		t.Log("Properties 'server.aliases[-1]' keeps the element it was created for.")
+	} else {
+		t.Error("Properties 'server.aliases[-1]' does not keep the element it was created for:", mail, smtp)
+	}
+}
+
+func TestPatchRoundTrip(t *testing.T) {
+
+	a, err := config.ReadProperties(strings.NewReader(TestDiffConfigDataA))
+	if err != nil {
+		t.Fatal("Error reading config properties:", err)
+	}
+	b, err := config.ReadProperties(strings.NewReader(TestDiffConfigDataB))
+	if err != nil {
+		t.Fatal("Error reading config properties:", err)
+	}
+
+	var buf bytes.Buffer
+	err = config.WritePatch(&buf, a, b, nil)
+	if err != nil {
+		t.Fatal("Error writing JSON Patch:", err)
+	}
+	err = a.ApplyPatch(&buf)
+	if err != nil {
+		t.Fatal("Error applying JSON Patch:", err)
+	}
+	if changes := config.Diff(a, b, nil); len(changes) == 0 {
+		t.Log("JSON Patch written by WritePatch turns a into b.")
+	} else {
+		t.Error("JSON Patch written by WritePatch does not turn a into b:", changes)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config_test
+
+import (
+	"config"
+	"strings"
+	"testing"
+)
+
+var TestPathConfigData = `{
+	"hosts":{
+		"db.example.com":{ "port":5432 },
+		"a[1]":"bracket",
+		"quote\"d":"quoted"
+	},
+	"list":[ "a", "b", "c", "d" ],
+	"matrix":[ [ 1, 2 ], [ 3, 4, 5 ] ]
+}`
+
+func TestPath(t *testing.T) {
+
+	t.Log("Read the following JSON config data:\n" + TestPathConfigData)
+
+	properties, err := config.ReadProperties(strings.NewReader(TestPathConfigData))
+	if err == nil {
+		t.Log("Success reading config properties.")
+	} else {
+		t.Fatal("Error reading config properties:", err)
+	}
+
+	ints := []struct {
+		name  string
+		value int64
+	}{
+		{`hosts["db.example.com"].port`, 5432},
+		{`hosts['db.example.com'].port`, 5432},
+		{`hosts.db\.example\.com.port`, 5432},
+		{"matrix[1][2]", 5},
+		{"matrix[-1][-3]", 3},
+		{"matrix[0][-1]", 2},
+	}
+	for _, v := range ints {
+		if i, err := properties.Int64(v.name); err == nil && i == v.value {
+			t.Log("Int64 value for '"+v.name+"' is", i)
+		} else {
+			t.Error("Int64 value for '"+v.name+"' is not", v.value, ":", i, err)
+		}
+	}
+
+	strs := []struct {
+		name, value string
+	}{
+		{"list[-1]", "d"},
+		{`hosts.a\[1\]`, "bracket"},
+		{`hosts["a[1]"]`, "bracket"},
+		{`hosts["quote\"d"]`, "quoted"},
+	}
+	for _, v := range strs {
+		if s, err := properties.String(v.name); err == nil && s == v.value {
+			t.Log("String value for '"+v.name+"' is", s)
+		} else {
+			t.Error("String value for '"+v.name+"' is not", v.value, ":", s, err)
+		}
+	}
+
+	slices := []struct {
+		name, value string
+	}{
+		{"list[1:3]", "b,c"},
+		{"list[:2]", "a,b"},
+		{"list[-2:]", "c,d"},
+		{"list[2:10]", "c,d"},
+		{"list[3:1]", ""},
+	}
+	for _, v := range slices {
+		sub, err := properties.Properties(v.name)
+		if err != nil {
+			t.Error("Error getting properties '"+v.name+"':", err)
+			continue
+		}
+		s, err := sub.Strings()
+		if err == nil && strings.Join(s, ",") == v.value {
+			t.Log("Strings value for '"+v.name+"' is", s)
+		} else {
+			t.Error("Strings value for '"+v.name+"' is not", v.value, ":", s, err)
+		}
+	}
+
+	_, err = properties.String("list[-5]")
+	if e, ok := err.(*config.IndexOutOfRangeError); ok && e.Index == -5 {
+		t.Log("IndexOutOfRangeError for 'list[-5]':", err)
+	} else {
+		t.Error("Error for 'list[-5]' is not an IndexOutOfRangeError:", err)
+	}
+
+	_, err = properties.String(`hosts.db\.example\.com.user`)
+	if e, ok := err.(*config.NotFoundError); ok && e.Name == `hosts["db.example.com"].user` {
+		t.Log("NotFoundError names the quoted key:", err)
+	} else {
+		t.Error("NotFoundError does not name the quoted key:", err)
+	}
+
+	invalid := []struct {
+		name   string
+		offset int
+	}{
+		{"list[1", 4},
+		{"list[x]", 5},
+		{"list[1:y]", 5},
+		{`hosts["db`, 5},
+		{"list[0]x", 7},
+		{"list]", 4},
+		{`list\`, 4},
+	}
+	for _, v := range invalid {
+		_, err = properties.String(v.name)
+		if e, ok := err.(*config.NameError); ok && e.Offset == v.offset {
+			t.Log("NameError for '"+v.name+"':", err)
+		} else {
+			t.Error("Error for '"+v.name+"' is not a NameError at offset", v.offset, ":", err)
+		}
+	}
+
+	if err = properties.Set("z", "list[-1]"); err == nil {
+		s, _ := properties.String("list[3]")
+		if s == "z" {
+			t.Log("Set with a negative index replaces the last element.")
+		} else {
+			t.Error("Set with a negative index does not replace the last element:", s)
+		}
+	} else {
+		t.Error("Error setting 'list[-1]':", err)
+	}
+}
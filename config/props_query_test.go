@@ -0,0 +1,123 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config_test
+
+import (
+	"os"
+	"fmt"
+	"config"
+	"strings"
+	"testing"
+)
+
+var TestQueryConfigData = `{
+	"services":{
+		"api":{ "port":8080, "enabled":true, "timeout":"5s" },
+		"db":{ "port":5432, "enabled":false },
+		"web":{ "port":80, "enabled":true, "client":{ "timeout":"30s" } }
+	},
+	"users":[
+		{ "name":"alice", "password":"secret1", "age":31 },
+		{ "name":"bob", "password":"secret2", "age":25 },
+		{ "name":"carol", "age":40 }
+	],
+	"timeout":"1m"
+}`
+
+func TestQuery(t *testing.T) {
+
+	t.Log("Read the following JSON config data:\n" + TestQueryConfigData)
+
+	properties, err := config.ReadProperties(strings.NewReader(TestQueryConfigData))
+	if err == nil {
+		t.Log("Success reading config properties.")
+	} else {
+		t.Fatal("Error reading config properties:", err)
+	}
+
+	queries := []struct {
+		query, result string
+	}{
+		{"services.*.port", "services.api.port=8080 services.db.port=5432 services.web.port=80"},
+		{"users[*].password", "users[0].password=secret1 users[1].password=secret2"},
+		{"$..timeout", "timeout=1m services.api.timeout=5s services.web.client.timeout=30s"},
+		{"services[?(@.enabled==true)].port", "services.api.port=8080 services.web.port=80"},
+		{"users[?(@.age >= 31)].name", "users[0].name=alice users[2].name=carol"},
+		{"users[?(@.name != 'bob')].age", "users[0].age=31 users[2].age=40"},
+		{"users[?(@.password)].name", "users[0].name=alice users[1].name=bob"},
+		{"users[-1].name", "users[2].name=carol"},
+		{"users[:2].age", "users[0].age=31 users[1].age=25"},
+		{"services.*.missing", ""},
+	}
+	for _, q := range queries {
+		matches, err := properties.Query(q.query)
+		if err != nil {
+			t.Error("Error querying '"+q.query+"':", err)
+			continue
+		}
+		result := make([]string, len(matches))
+		for i, m := range matches {
+			result[i] = fmt.Sprint(m.Name, "=", m.Value)
+		}
+		if s := strings.Join(result, " "); s == q.result {
+			t.Log("Query '"+q.query+"' matches:", s)
+		} else {
+			t.Error("Query '"+q.query+"' does not match", q.result, ":", s)
+		}
+	}
+
+	services, err := properties.Properties("services")
+	if err != nil {
+		t.Fatal("Error getting properties 'services':", err)
+	}
+	matches, err := services.Query("*.port")
+	if err == nil && len(matches) == 3 && matches[0].Name == "api.port" {
+		port, _ := services.Int64(matches[0].Name)
+		t.Log("Query names are relative to the properties queried:", matches[0].Name, port)
+	} else {
+		t.Error("Query names are not relative to the properties queried:", matches, err)
+	}
+
+	matches, err = properties.Query("services.api")
+	if err != nil || len(matches) != 1 {
+		t.Fatal("Error querying 'services.api':", matches, err)
+	}
+	if m, ok := matches[0].Value.(map[string]interface{}); ok {
+		m["port"] = 0
+	}
+	if i, _ := properties.Int64("services.api.port"); i == 8080 {
+		t.Log("Changing a matched map does not change the properties.")
+	} else {
+		t.Error("Changing a matched map changes the properties:", i)
+	}
+
+	defer restoreEnv(os.Environ())
+	os.Setenv("TESTQUERY_SERVICES_API_ENABLED", "false")
+	os.Setenv("TESTQUERY_SERVICES_DB_PORT", "6543")
+	properties.SetEnvOverlay(&config.EnvOverlay{Prefix: "TESTQUERY_"})
+	for query, result := range map[string]string{
+		"services[?(@.enabled==true)].port": "services.web.port=80",
+		"services[?(@.port > 6000)].port":   "services.api.port=8080 services.db.port=6543",
+	} {
+		matches, err = properties.Query(query)
+		s := make([]string, len(matches))
+		for i, m := range matches {
+			s[i] = fmt.Sprint(m.Name, "=", m.Value)
+		}
+		if err == nil && strings.Join(s, " ") == result {
+			t.Log("Query '"+query+"' compares values from the environment:", result)
+		} else {
+			t.Error("Query '"+query+"' does not compare values from the environment:", s, err)
+		}
+	}
+	properties.SetEnvOverlay(nil)
+
+	for _, query := range []string{"users[?(@.age > )]", "users[?(@.age > 1)", "users[?(age > 1)]", "timeout.."} {
+		if _, err = properties.Query(query); err != nil {
+			t.Log("Query '"+query+"' is not valid:", err)
+		} else {
+			t.Error("Query '"+query+"' is valid.")
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config_test
+
+import (
+	"os"
+	"config"
+	"strings"
+	"testing"
+)
+
+var TestSchemaData = `{
+	"type":"object",
+	"required":[ "name", "port", "mode" ],
+	"properties":{
+		"name":{ "type":"string", "pattern":"^[a-z]+$" },
+		"port":{ "$ref":"#/$defs/port" },
+		"mode":{ "enum":[ "fast", "safe" ] },
+		"hosts":{
+			"type":"array",
+			"minItems":1,
+			"items":{ "type":"string", "maxLength":8 }
+		},
+		"ratio":{ "type":"number", "exclusiveMaximum":1 }
+	},
+	"additionalProperties":false,
+	"$defs":{
+		"port":{ "type":"integer", "minimum":1, "maximum":65535 }
+	}
+}`
+
+var TestSchemaConfigData = `{
+	"name":"Server1",
+	"port":70000,
+	"hosts":[ "alpha", "a-very-long-host" ],
+	"ratio":1.0,
+	"debug":true
+}`
+
+func TestSchema(t *testing.T) {
+
+	t.Log("Read the following JSON schema data:\n" + TestSchemaData)
+
+	schema, err := config.ReadSchema(strings.NewReader(TestSchemaData))
+	if err == nil {
+		t.Log("Success reading schema.")
+	} else {
+		t.Fatal("Error reading schema:", err)
+	}
+
+	properties, err := config.ReadProperties(strings.NewReader(TestSchemaConfigData))
+	if err != nil {
+		t.Fatal("Error reading config properties:", err)
+	}
+
+	err = properties.Validate(schema)
+	serr, ok := err.(*config.SchemaError)
+	if !ok {
+		t.Fatal("Validate did not return a SchemaError:", err)
+	}
+	want := []string{"mode", "debug", "hosts[1]", "name", "port", "ratio"}
+	var names []string
+	for _, e := range serr.Errors {
+		names = append(names, e.Name)
+	}
+	if strings.Join(names, ",") == strings.Join(want, ",") {
+		t.Log("Validate reported every violation:", err)
+	} else {
+		t.Error("Validate did not report", want, ":", err)
+	}
+
+	good := `{ "name":"server", "port":8080, "mode":"safe", "hosts":[ "alpha" ], "ratio":0.5 }`
+	properties, _ = config.ReadProperties(strings.NewReader(good))
+	err = properties.Validate(schema)
+	if err == nil {
+		t.Log("Validate accepted valid properties.")
+	} else {
+		t.Error("Validate rejected valid properties:", err)
+	}
+
+	_, err = config.ReadSchema(strings.NewReader(`{ "$ref":"#/$defs/missing" }`))
+	if err != nil {
+		t.Log("ReadSchema rejected an unresolved reference:", err)
+	} else {
+		t.Error("ReadSchema accepted an unresolved reference.")
+	}
+	_, err = config.ReadSchema(strings.NewReader(`{ "$ref":"#/x/code", "x":{ "code":{ "pattern":"(" } } }`))
+	if err != nil {
+		t.Log("ReadSchema rejected an invalid pattern reached through a reference:", err)
+	} else {
+		t.Error("ReadSchema accepted an invalid pattern reached through a reference.")
+	}
+
+	schema, err = config.ReadSchema(strings.NewReader(`{ "properties":{
+		"code":{ "$ref":"#/x/code" },
+		"big":{ "maximum":9007199254740992 },
+		"exact":{ "enum":[ 9007199254740992 ] }
+	}, "x":{ "code":{ "pattern":"^[A-Z]+$" } } }`))
+	if err != nil {
+		t.Fatal("Error reading JSON schema:", err)
+	}
+	properties, err = config.ReadProperties(strings.NewReader(`{ "code":"abc", "big":9007199254740993, "exact":9007199254740993 }`))
+	if err != nil {
+		t.Fatal("Error reading config properties:", err)
+	}
+	err = properties.Validate(schema)
+	if serr, ok := err.(*config.SchemaError); ok && len(serr.Errors) == 3 {
+		t.Log("Validate checks referenced patterns and compares integers exactly:", err)
+	} else {
+		t.Error("Validate does not check referenced patterns or compare integers exactly:", err)
+	}
+
+	properties, err = config.ReadProperties(strings.NewReader(`{ "app":{ "code":"abc" } }`))
+	if err != nil {
+		t.Fatal("Error reading config properties:", err)
+	}
+	app, err := properties.Properties("app")
+	if err != nil {
+		t.Fatal("Error getting properties 'app':", err)
+	}
+	err = app.Validate(schema)
+	if serr, ok := err.(*config.SchemaError); ok && len(serr.Errors) == 1 && serr.Errors[0].Name == "app.code" {
+		t.Log("Validate names violations by the full property name:", err)
+	} else {
+		t.Error("Validate does not name violations by the full property name:", err)
+	}
+}
+
+func TestSchemaFile(t *testing.T) {
+
+	f, err := os.Open("testdata/schema.json")
+	if err != nil {
+		t.Fatal("Error opening schema file:", err)
+	}
+	schema, err := config.ReadSchema(f)
+	f.Close()
+	if err != nil {
+		t.Fatal("Error reading schema file:", err)
+	}
+
+	_, err = config.ReadConfigFile(TestFileName, schema)
+	if err == nil {
+		t.Log("Config file matches schema.")
+	} else {
+		t.Error("Config file does not match schema:", err)
+	}
+
+	schema, err = config.ReadSchema(strings.NewReader(`{ "required":[ "timeout" ] }`))
+	if err != nil {
+		t.Fatal("Error reading schema:", err)
+	}
+	_, err = config.ReadConfigFile("testdata/config.yaml", schema)
+	if _, ok := err.(*config.SchemaError); ok {
+		t.Log("Config file without timeout does not match schema:", err)
+	} else {
+		t.Error("Config file without timeout did not return a SchemaError:", err)
+	}
+}
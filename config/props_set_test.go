@@ -0,0 +1,116 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config_test
+
+import (
+	"config"
+	"strings"
+	"testing"
+)
+
+var TestSetConfigData = `{
+	"string1":"Hello World",
+	"level2":{
+		"float2":3.0e-3,
+		"array3":[ "one", "two", "three" ]
+	}
+}`
+
+func TestSet(t *testing.T) {
+
+	t.Log("Read the following JSON config data:\n" + TestSetConfigData)
+
+	properties, err := config.ReadProperties(strings.NewReader(TestSetConfigData))
+	if err == nil {
+		t.Log("Success reading config properties.")
+	} else {
+		t.Fatal("Error reading config properties:", err)
+	}
+
+	err = properties.Set("Goodbye World", "string1")
+	if err != nil {
+		t.Error("Error setting string value for property 'string1':", err)
+	}
+	if s, _ := properties.String("string1"); s == "Goodbye World" {
+		t.Log("String value for 'string1' is 'Goodbye World'.")
+	} else {
+		t.Error("String value for 'string1' is not 'Goodbye World'.")
+	}
+
+	err = properties.Set(42, "a.b[2].c")
+	if err != nil {
+		t.Error("Error setting int value for property 'a.b[2].c':", err)
+	}
+	if i, _ := properties.Int64("a", "b", 2, "c"); i == 42 {
+		t.Log("Int64 value for created property 'a.b[2].c' is 42.")
+	} else {
+		t.Error("Int64 value for created property 'a.b[2].c' is not 42.")
+	}
+	if p, _ := properties.Property("a.b[1]"); p == nil {
+		t.Log("Value for grown array element 'a.b[1]' is nil.")
+	} else {
+		t.Error("Value for grown array element 'a.b[1]' is not nil.")
+	}
+
+	err = properties.Set(1, "a.b[2000000000]")
+	if _, ok := err.(*config.IndexOutOfRangeError); ok {
+		t.Log("Setting property 'a.b[2000000000]' returns IndexOutOfRangeError:", err)
+	} else {
+		t.Error("Setting property 'a.b[2000000000]' does not return IndexOutOfRangeError:", err)
+	}
+
+	err = properties.Set([]string{"x", "y"}, "level2.array3[4]")
+	if err != nil {
+		t.Error("Error setting slice value for property 'level2.array3[4]':", err)
+	}
+	if s, _ := properties.String("level2", "array3", 4, 1); s == "y" {
+		t.Log("String value for 'level2.array3[4][1]' is 'y'.")
+	} else {
+		t.Error("String value for 'level2.array3[4][1]' is not 'y'.")
+	}
+
+	err = properties.Set(true, "level2.float2.bool3")
+	if e, ok := err.(*config.TypeMismatchError); ok && e.Segment == "level2.float2" {
+		t.Log("Setting property 'level2.float2.bool3' returns TypeMismatchError:", err)
+	} else {
+		t.Error("Setting property 'level2.float2.bool3' does not return TypeMismatchError:", err)
+	}
+	level2, err := properties.Properties("level2")
+	if err != nil {
+		t.Fatal("Error getting Properties value from property 'level2':", err)
+	}
+	err = level2.Delete("float2.bool3")
+	if e, ok := err.(*config.TypeMismatchError); ok && e.Name == "level2.float2.bool3" {
+		t.Log("Deleting property 'float2.bool3' of 'level2' names the full property:", err)
+	} else {
+		t.Error("Deleting property 'float2.bool3' of 'level2' does not name the full property:", err)
+	}
+
+	err = properties.Delete("level2.array3[0]")
+	if err != nil {
+		t.Error("Error deleting property 'level2.array3[0]':", err)
+	}
+	if s, _ := properties.String("level2.array3[0]"); s == "two" {
+		t.Log("String value for 'level2.array3[0]' is 'two' after delete.")
+	} else {
+		t.Error("String value for 'level2.array3[0]' is not 'two' after delete.")
+	}
+
+	err = properties.Delete("level2", "float2")
+	if err != nil {
+		t.Error("Error deleting property 'level2.float2':", err)
+	}
+	if _, err = properties.Property("level2.float2"); err != nil {
+		t.Log("Property 'level2.float2' does not exist after delete.")
+	} else {
+		t.Error("Property 'level2.float2' exists after delete.")
+	}
+
+	err = properties.Delete("level2.missing")
+	if err != nil {
+		t.Log("Deleting missing property 'level2.missing' returns error:", err)
+	} else {
+		t.Error("Deleting missing property 'level2.missing' does not return error.")
+	}
+}
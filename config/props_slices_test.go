@@ -0,0 +1,118 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config_test
+
+import (
+	"os"
+	"config"
+	"strings"
+	"testing"
+)
+
+var TestSlicesConfigData = `{
+	"hosts":[ "alpha", "beta", "gamma" ],
+	"ports":[ 8080, 8081, 9000 ],
+	"ratios":[ 0.5, 1, 2.5 ],
+	"flags":[ true, false ],
+	"mixed":[ "a", "b", 3 ],
+	"labels":{ "env":"prod", "team":"core" },
+	"servers":{
+		"web":{ "port":80 },
+		"db":{ "port":5432 }
+	}
+}`
+
+func TestSlices(t *testing.T) {
+
+	t.Log("Read the following JSON config data:\n" + TestSlicesConfigData)
+
+	properties, err := config.ReadProperties(strings.NewReader(TestSlicesConfigData))
+	if err == nil {
+		t.Log("Success reading config properties.")
+	} else {
+		t.Fatal("Error reading config properties:", err)
+	}
+
+	s, err := properties.Strings("hosts")
+	if err == nil && strings.Join(s, ",") == "alpha,beta,gamma" {
+		t.Log("Strings value for 'hosts' is", s)
+	} else {
+		t.Error("Strings value for 'hosts' is not [alpha beta gamma]:", s, err)
+	}
+
+	i, err := properties.Int64s("ports")
+	if err == nil && len(i) == 3 && i[0] == 8080 && i[2] == 9000 {
+		t.Log("Int64s value for 'ports' is", i)
+	} else {
+		t.Error("Int64s value for 'ports' is not [8080 8081 9000]:", i, err)
+	}
+
+	f, err := properties.Float64s("ratios")
+	if err == nil && len(f) == 3 && f[0] == 0.5 && f[2] == 2.5 {
+		t.Log("Float64s value for 'ratios' is", f)
+	} else {
+		t.Error("Float64s value for 'ratios' is not [0.5 1 2.5]:", f, err)
+	}
+
+	b, err := properties.Bools("flags")
+	if err == nil && len(b) == 2 && b[0] && !b[1] {
+		t.Log("Bools value for 'flags' is", b)
+	} else {
+		t.Error("Bools value for 'flags' is not [true false]:", b, err)
+	}
+
+	_, err = properties.Strings("mixed")
+	if e, ok := err.(*config.TypeMismatchError); ok && e.Name == "mixed[2]" {
+		t.Log("Strings value for 'mixed' reports the bad element:", err)
+	} else {
+		t.Error("Strings value for 'mixed' did not report element 2:", err)
+	}
+
+	_, err = properties.Int64s("ratios")
+	if e, ok := err.(*config.ValueError); ok && e.Name == "ratios[0]" {
+		t.Log("Int64s value for 'ratios' reports the bad element:", err)
+	} else {
+		t.Error("Int64s value for 'ratios' did not report element 0:", err)
+	}
+
+	if d := properties.StringsDefault([]string{"none"}, "missing"); len(d) == 1 && d[0] == "none" {
+		t.Log("Strings value for 'missing' is the default.")
+	} else {
+		t.Error("Strings value for 'missing' is not the default:", d)
+	}
+
+	m, err := properties.StringMap("labels")
+	if err == nil && len(m) == 2 && m["env"] == "prod" && m["team"] == "core" {
+		t.Log("StringMap value for 'labels' is", m)
+	} else {
+		t.Error("StringMap value for 'labels' is not {env:prod team:core}:", m, err)
+	}
+
+	_, err = properties.StringMap("servers")
+	if e, ok := err.(*config.TypeMismatchError); ok && e.Name == "servers.db" {
+		t.Log("StringMap value for 'servers' reports the bad element:", err)
+	} else {
+		t.Error("StringMap value for 'servers' did not report element 'db':", err)
+	}
+
+	pm, err := properties.PropertiesMap("servers")
+	if err == nil && len(pm) == 2 {
+		if port := pm["db"].Int64Default(0, "port"); port == 5432 {
+			t.Log("PropertiesMap value for 'servers.db.port' is 5432.")
+		} else {
+			t.Error("PropertiesMap value for 'servers.db.port' is not 5432:", port)
+		}
+	} else {
+		t.Error("Error getting PropertiesMap value for 'servers':", err)
+	}
+
+	os.Setenv("TESTSLICES_HOSTS", "delta, epsilon")
+	properties.SetEnvOverlay(&config.EnvOverlay{Prefix: "TESTSLICES_"})
+	s, err = properties.Strings("hosts")
+	if err == nil && strings.Join(s, ",") == "delta,epsilon" {
+		t.Log("Strings value for 'hosts' is", s, "from environment.")
+	} else {
+		t.Error("Strings value for 'hosts' is not [delta epsilon] from environment:", s, err)
+	}
+}
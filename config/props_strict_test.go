@@ -0,0 +1,71 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config_test
+
+import (
+	"os"
+	"config"
+	"strings"
+	"testing"
+)
+
+var TestStrictConfigData = `{
+	"server":{
+		"host":"localhost",
+		"port":"8080",
+		"debug":"yes"
+	}
+}`
+
+func TestStrict(t *testing.T) {
+
+	t.Log("Read the following JSON config data:\n" + TestStrictConfigData)
+
+	properties, err := config.ReadProperties(strings.NewReader(TestStrictConfigData))
+	if err == nil {
+		t.Log("Success reading config properties.")
+	} else {
+		t.Fatal("Error reading config properties:", err)
+	}
+
+	if port := properties.Int64Default(80, "server.port"); port == 80 {
+		t.Log("Int64 value for 'server.port' is the default without strict mode.")
+	} else {
+		t.Error("Int64 value for 'server.port' is not the default without strict mode:", port)
+	}
+
+	var names []string
+	properties.SetStrict(func(name string, err os.Error) {
+		names = append(names, name)
+	})
+
+	server, err := properties.Properties("server")
+	if err != nil {
+		t.Fatal("Error getting properties 'server':", err)
+	}
+	port := server.Int64Default(80, "port")
+	debug := server.BoolDefault(false, "debug")
+	timeout := server.DurationDefault(30e9, "timeout")
+	host := server.StringDefault("", "host")
+	if port == 80 && !debug && timeout == 30e9 && host == "localhost" {
+		t.Log("Default values are returned in strict mode.")
+	} else {
+		t.Error("Default values are not returned in strict mode:", port, debug, timeout, host)
+	}
+	if strings.Join(names, ",") == "server.port,server.debug" {
+		t.Log("Strict mode handler was called for invalid values:", names)
+	} else {
+		t.Error("Strict mode handler was not called for only the invalid values:", names)
+	}
+
+	properties.SetStrict(config.PanicOnInvalid)
+	defer func() {
+		if r := recover(); r != nil {
+			t.Log("PanicOnInvalid panics for an invalid value:", r)
+		} else {
+			t.Error("PanicOnInvalid did not panic for an invalid value.")
+		}
+	}()
+	properties.Int64Default(80, "server.port")
+}
@@ -0,0 +1,107 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config_test
+
+import (
+	"config"
+	"strings"
+	"testing"
+)
+
+var TestTOMLConfigData = `# test config
+title = "TOML \"test\""
+created = 1979-05-27T07:32:00Z
+
+[server]
+host = 'localhost'
+port = 8_080
+mask = 0xff
+ratio = 5e-1
+debug = false
+tls.enabled = true
+
+[[users]]
+name = "user1"
+roles = [ "admin",
+          "dev", ]  # trailing comma
+
+[[users]]
+name = "user2"
+limits = { read = 10, write = 20 }
+motd = """
+Hello \
+  World"""
+`
+
+func TestTOML(t *testing.T) {
+
+	t.Log("Read the following TOML config data:\n" + TestTOMLConfigData)
+
+	properties, err := config.ReadTOML(strings.NewReader(TestTOMLConfigData))
+	if err == nil {
+		t.Log("Success reading config properties.")
+	} else {
+		t.Fatal("Error reading config properties:", err)
+	}
+
+	strs := map[string]string{
+		"title":             `TOML "test"`,
+		"created":           "1979-05-27T07:32:00Z",
+		"server.host":       "localhost",
+		"users[0].roles[1]": "dev",
+		"users[1].name":     "user2",
+		"users[1].motd":     "Hello World",
+	}
+	for name, expected := range strs {
+		if s, err := properties.String(name); s == expected {
+			t.Logf("String value for '%s' is %q.", name, expected)
+		} else {
+			t.Errorf("String value for '%s' is not %q: %q %v", name, expected, s, err)
+		}
+	}
+
+	ints := map[string]int64{
+		"server.port":          8080,
+		"server.mask":          255,
+		"users[1].limits.read": 10,
+	}
+	for name, expected := range ints {
+		if i, err := properties.Int64(name); i == expected {
+			t.Logf("Int64 value for '%s' is %d.", name, expected)
+		} else {
+			t.Errorf("Int64 value for '%s' is not %d: %d %v", name, expected, i, err)
+		}
+	}
+
+	if f, _ := properties.Float64("server.ratio"); f == 0.5 {
+		t.Log("Float64 value for 'server.ratio' is 0.5.")
+	} else {
+		t.Error("Float64 value for 'server.ratio' is not 0.5.")
+	}
+	if b, _ := properties.Bool("server.tls.enabled"); b == true {
+		t.Log("Bool value for dotted key 'server.tls.enabled' is true.")
+	} else {
+		t.Error("Bool value for dotted key 'server.tls.enabled' is not true.")
+	}
+
+	_, err = config.ReadTOML(strings.NewReader("a = 1\na = 2\n"))
+	if err != nil {
+		t.Log("Reading TOML with duplicate key returns error:", err)
+	} else {
+		t.Error("Reading TOML with duplicate key does not return error.")
+	}
+
+	_, err = config.ReadTOML(strings.NewReader("[a]\nx = 1\n[b]\n[a]\ny = 2\n"))
+	if e, ok := err.(*config.ParseError); ok && e.Line == 4 {
+		t.Log("Reading TOML with a table defined twice returns error:", err)
+	} else {
+		t.Error("Reading TOML with a table defined twice does not return error at line 4:", err)
+	}
+	_, err = config.ReadTOML(strings.NewReader("[a.b]\nx = 1\n[a]\ny = 2\n[[c]]\n[c.d]\n[[c]]\n[c.d]\n"))
+	if err == nil {
+		t.Log("Reading TOML with implicit tables and arrays of tables succeeds.")
+	} else {
+		t.Error("Reading TOML with implicit tables and arrays of tables returns error:", err)
+	}
+}
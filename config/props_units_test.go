@@ -0,0 +1,114 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config_test
+
+import (
+	"time"
+	"config"
+	"strings"
+	"testing"
+)
+
+var TestUnitsConfigData = `{
+	"timeout":"1h30m",
+	"interval":"1.5s",
+	"retry":30,
+	"cache":"512MiB",
+	"disk":"1.5 GB",
+	"block":4096,
+	"start":"2026-10-18T00:00:00Z",
+	"day":"2026-10-18",
+	"bad":"30 parsecs",
+	"negative":-1,
+	"negativeUnit":"-1KB"
+}`
+
+func TestUnits(t *testing.T) {
+
+	t.Log("Read the following JSON config data:\n" + TestUnitsConfigData)
+
+	properties, err := config.ReadProperties(strings.NewReader(TestUnitsConfigData))
+	if err == nil {
+		t.Log("Success reading config properties.")
+	} else {
+		t.Fatal("Error reading config properties:", err)
+	}
+
+	durations := []struct {
+		name string
+		ns   int64
+	}{
+		{"timeout", 5400e9},
+		{"interval", 1500e6},
+		{"retry", 30e9},
+	}
+	for _, d := range durations {
+		ns, err := properties.Duration(d.name)
+		if err == nil && ns == d.ns {
+			t.Log("Duration value for '"+d.name+"' is", ns)
+		} else {
+			t.Error("Duration value for '"+d.name+"' is not", d.ns, ":", ns, err)
+		}
+	}
+	if ns := properties.DurationDefault(7, "bad"); ns == 7 {
+		t.Log("Duration value for 'bad' is 7.")
+	} else {
+		t.Error("Duration value for 'bad' is not 7:", ns)
+	}
+
+	sizes := []struct {
+		name  string
+		bytes int64
+	}{
+		{"cache", 512 << 20},
+		{"disk", 1500e6},
+		{"block", 4096},
+	}
+	for _, s := range sizes {
+		n, err := properties.ByteSize(s.name)
+		if err == nil && n == s.bytes {
+			t.Log("ByteSize value for '"+s.name+"' is", n)
+		} else {
+			t.Error("ByteSize value for '"+s.name+"' is not", s.bytes, ":", n, err)
+		}
+	}
+	for _, name := range []string{"bad", "negative", "negativeUnit"} {
+		_, err := properties.ByteSize(name)
+		if e, ok := err.(*config.ValueError); ok && e.Name == name {
+			t.Log("ByteSize value for '"+name+"' is not valid:", err)
+		} else {
+			t.Error("ByteSize value for '" + name + "' did not return an error.")
+		}
+	}
+
+	config.PropByteSizeUnit = 1024
+	if n, _ := properties.ByteSize("block"); n == 4096*1024 {
+		t.Log("ByteSize value for 'block' is 4096 KiB.")
+	} else {
+		t.Error("ByteSize value for 'block' is not 4096 KiB:", n)
+	}
+	config.PropByteSizeUnit = 1
+
+	tm, err := properties.Time("start")
+	if err == nil && tm.Format(time.RFC3339) == "2026-10-18T00:00:00Z" {
+		t.Log("Time value for 'start' is", tm.Format(time.RFC3339))
+	} else {
+		t.Error("Time value for 'start' is not 2026-10-18T00:00:00Z:", err)
+	}
+	if tm := properties.TimeDefault(nil, "day"); tm == nil {
+		t.Log("Time value for 'day' is not RFC 3339.")
+	} else {
+		t.Error("Time value for 'day' did not return the default.")
+	}
+
+	layouts := config.PropTimeLayouts
+	config.PropTimeLayouts = append(layouts, "2006-01-02")
+	tm, err = properties.Time("day")
+	if err == nil && tm.Format("2006-01-02") == "2026-10-18" {
+		t.Log("Time value for 'day' is", tm.Format("2006-01-02"))
+	} else {
+		t.Error("Time value for 'day' is not 2026-10-18:", err)
+	}
+	config.PropTimeLayouts = layouts
+}
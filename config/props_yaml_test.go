@@ -0,0 +1,120 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config_test
+
+import (
+	"config"
+	"strings"
+	"testing"
+)
+
+var TestYAMLConfigData = `# test config
+server:
+  host: localhost   # trailing comment
+  port: 8080
+  debug: false
+  ratio: 0.5
+  name: "quoted: \"value\""
+  path: 'it''s'
+  empty:
+hosts:
+- one
+- two
+users:
+  - name: user1
+    roles: [ admin, "dev" ]
+  - name: user2
+    roles: []
+limits: { read: 10, write: 20 }
+motd: |
+  Hello
+  World
+folded: >-
+  one
+  two
+`
+
+func TestYAML(t *testing.T) {
+
+	t.Log("Read the following YAML config data:\n" + TestYAMLConfigData)
+
+	properties, err := config.ReadYAML(strings.NewReader(TestYAMLConfigData))
+	if err == nil {
+		t.Log("Success reading config properties.")
+	} else {
+		t.Fatal("Error reading config properties:", err)
+	}
+
+	strs := map[string]string{
+		"server.host":       "localhost",
+		"server.name":       `quoted: "value"`,
+		"server.path":       "it's",
+		"hosts[1]":          "two",
+		"users[0].name":     "user1",
+		"users[0].roles[1]": "dev",
+		"motd":              "Hello\nWorld\n",
+		"folded":            "one two",
+	}
+	for name, expected := range strs {
+		if s, err := properties.String(name); s == expected {
+			t.Logf("String value for '%s' is %q.", name, expected)
+		} else {
+			t.Errorf("String value for '%s' is not %q: %q %v", name, expected, s, err)
+		}
+	}
+
+	if i, _ := properties.Int64("server.port"); i == 8080 {
+		t.Log("Int64 value for 'server.port' is 8080.")
+	} else {
+		t.Error("Int64 value for 'server.port' is not 8080.")
+	}
+	if f, _ := properties.Float64("server.ratio"); f == 0.5 {
+		t.Log("Float64 value for 'server.ratio' is 0.5.")
+	} else {
+		t.Error("Float64 value for 'server.ratio' is not 0.5.")
+	}
+	if b, err := properties.Bool("server.debug"); err == nil && b == false {
+		t.Log("Bool value for 'server.debug' is false.")
+	} else {
+		t.Error("Bool value for 'server.debug' is not false.")
+	}
+	if p, err := properties.Property("server.empty"); err == nil && p == nil {
+		t.Log("Value for 'server.empty' is nil.")
+	} else {
+		t.Error("Value for 'server.empty' is not nil.")
+	}
+	if i, _ := properties.Int64("limits.write"); i == 20 {
+		t.Log("Int64 value for flow map 'limits.write' is 20.")
+	} else {
+		t.Error("Int64 value for flow map 'limits.write' is not 20.")
+	}
+	if p, _ := properties.Property("users[1].roles"); p != nil && len(p.([]interface{})) == 0 {
+		t.Log("Value for 'users[1].roles' is an empty array.")
+	} else {
+		t.Error("Value for 'users[1].roles' is not an empty array.")
+	}
+
+	_, err = config.ReadYAML(strings.NewReader("a: 1\n   b: 2\n"))
+	if err != nil {
+		t.Log("Reading YAML with bad indentation returns error:", err)
+	} else {
+		t.Error("Reading YAML with bad indentation does not return error.")
+	}
+
+	tabbed, err := config.ReadYAML(strings.NewReader("script: |\n  make\n  \tinstall\n"))
+	if err != nil {
+		t.Fatal("Error reading YAML block scalar with a tab:", err)
+	}
+	if s, _ := tabbed.String("script"); s == "make\n\tinstall\n" {
+		t.Log("Block scalar keeps a line beginning with a tab.")
+	} else {
+		t.Errorf("Block scalar does not keep a line beginning with a tab: %q", s)
+	}
+	_, err = config.ReadYAML(strings.NewReader("a:\n\tb: 2\n"))
+	if err != nil {
+		t.Log("Reading YAML indented with a tab returns error:", err)
+	} else {
+		t.Error("Reading YAML indented with a tab does not return error.")
+	}
+}
@@ -0,0 +1,454 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config
+
+import (
+	"os"
+	"bytes"
+	"strings"
+	"strconv"
+)
+
+// Match is a property value found by Query.
+type Match struct {
+	Name  string // the property name, relative to the Properties queried
+	Value interface{}
+}
+
+// Query retrieves the property values matched by a query, in the order
+// of the property tree with map keys sorted. A query is a property name
+// in which segments may also be:
+//
+//	*                  every value of a map or element of an array,
+//	                   also written [*], as in "services.*.port"
+//	..name             name at any depth, as in "..timeout"
+//	[?(@.path op v)]   every value of a map or element of an array for
+//	                   which the value at path compares to the literal v
+//	                   with op, one of ==, !=, <, <=, > and >=, as in
+//	                   "users[?(@.enabled==true)].name"
+//	[?(@.path)]        every value for which the value at path exists
+//
+// An index or slice selects array elements, a negative index counting
+// from the end. The literal in a filter is a JSON value other than a
+// map or array, or a string in single quotes, and "@" alone is the
+// value itself. Values that do not match, such as a key on a value
+// that is not a map, are skipped rather than reported as errors. The
+// values matched, and the values compared by filters, are found as by
+// Property, and a value compared is converted to the type of the
+// literal as by the getters. A syntax error in the query is returned
+// as a *NameError.
+func (p *Properties) Query(query string) ([]*Match, os.Error) {
+	steps, err := parseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	p.rlock()
+	nodes := []node{{nil, p.root}}
+	p.runlock()
+	for _, s := range steps {
+		nodes = s.apply(p, nodes)
+	}
+
+	matches := make([]*Match, len(nodes))
+	for i, n := range nodes {
+		v, err := p.found(n.path, n.value)
+		if err != nil {
+			return nil, err
+		}
+		matches[i] = &Match{formatPath(n.path), v}
+	}
+	return matches, nil
+}
+
+// found returns a value found at path in the property tree as Property
+// would return it, from the environment overlay if it has a value for
+// path, and with references expanded if interpolation is enabled.
+// Maps and arrays are copied, so the caller may keep them. It is
+// called without the lock held.
+func (p *Properties) found(path []segment, v interface{}) (interface{}, os.Error) {
+	p.rlock()
+	if e, ok := p.envLookup(joinPath(p.path, path)); ok {
+		v = e
+	} else {
+		v, _ = normalize(v)
+	}
+	p.runlock()
+	if p.interp {
+		var err os.Error
+		v, err = p.interpolate(v, path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if e, ok := v.(envValue); ok {
+		v = string(e)
+	}
+	return v, nil
+}
+
+// node is a value in the property tree and its path.
+type node struct {
+	path  []segment
+	value interface{}
+}
+
+// children returns the values of a map, in sorted key order,
+// or the elements of an array.
+func (n node) children() []node {
+	var nodes []node
+	switch v := n.value.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			nodes = append(nodes, node{appendPath(n.path, segment{key, false}), v[key]})
+		}
+	case []interface{}:
+		for i, elem := range v {
+			nodes = append(nodes, node{appendPath(n.path, segment{strconv.Itoa(i), true}), elem})
+		}
+	}
+	return nodes
+}
+
+// descend calls fn for n and every value below it.
+func (n node) descend(fn func(node)) {
+	fn(n)
+	for _, child := range n.children() {
+		child.descend(fn)
+	}
+}
+
+const (
+	stepKey    = iota // a key, index or slice
+	stepAll           // a wildcard
+	stepFilter        // a filter
+)
+
+// step is a segment of a query.
+type step struct {
+	kind    int
+	seg     segment // the key, index or slice of a stepKey
+	filter  *filter
+	descend bool // match at any depth, written ".."
+}
+
+// apply returns the values matched by s below the nodes. The values
+// are read with the lock of p held, and filters are then tested
+// without it, as they may look up references.
+func (s *step) apply(p *Properties, nodes []node) []node {
+	p.rlock()
+	var result []node
+	for _, n := range nodes {
+		if s.descend {
+			n.descend(func(d node) {
+				result = s.match(d, result)
+			})
+		} else {
+			result = s.match(n, result)
+		}
+	}
+	p.runlock()
+	if s.kind != stepFilter {
+		return result
+	}
+	var kept []node
+	for _, n := range result {
+		if s.filter.test(p, n.path) {
+			kept = append(kept, n)
+		}
+	}
+	return kept
+}
+
+// match appends the values matched by s below n to result. The
+// values of a filter are to be tested by the caller.
+func (s *step) match(n node, result []node) []node {
+	switch s.kind {
+	case stepAll, stepFilter:
+		return append(result, n.children()...)
+	}
+	switch v := n.value.(type) {
+	case map[string]interface{}:
+		if elem, ok := v[s.seg.key]; ok && !isSlice(s.seg) {
+			result = append(result, node{appendPath(n.path, s.seg), elem})
+		}
+	case []interface{}:
+		if isSlice(s.seg) {
+			lo, hi := sliceBounds(s.seg.key, len(v))
+			for i := lo; i < hi; i++ {
+				result = append(result, node{appendPath(n.path, segment{strconv.Itoa(i), true}), v[i]})
+			}
+		} else if idx, ok := arrayIndex(s.seg.key, len(v)); ok && idx >= 0 && idx < int64(len(v)) {
+			result = append(result, node{appendPath(n.path, segment{strconv.Itoa64(idx), true}), v[idx]})
+		}
+	}
+	return result
+}
+
+// filter is a test of a value in a query.
+type filter struct {
+	path  []segment // the path below the value tested
+	op    string    // the comparison, empty to test that path exists
+	value interface{}
+}
+
+// test reports whether the value at path, relative to p, passes the
+// filter. The value compared is found at f.path below it as by the
+// getters, from the environment overlay and with references expanded,
+// and converted to the type of the literal as by the getters.
+func (f *filter) test(p *Properties, path []segment) bool {
+	fpath := joinPath(path, f.path)
+	cur, err := p.lookup(fpath)
+	if err == nil && p.interp {
+		cur, err = p.interpolate(cur, fpath)
+	}
+	if err != nil {
+		return false
+	}
+	switch f.value.(type) {
+	case bool:
+		if b, ok := p.toBool(cur); ok {
+			cur = b
+		}
+	case Number:
+		if n, ok := p.toNumber(cur); ok {
+			cur = n
+		}
+	case string:
+		if s, ok := p.toString(cur); ok {
+			cur = s
+		}
+	}
+	if e, ok := cur.(envValue); ok {
+		cur = string(e)
+	}
+	switch f.op {
+	case "":
+		return true
+	case "==":
+		return sameValue(cur, f.value)
+	case "!=":
+		return !sameValue(cur, f.value)
+	}
+	c, ok := compare(cur, f.value)
+	if !ok {
+		return false
+	}
+	switch f.op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	}
+	return c >= 0
+}
+
+// compare returns -1, 0 or 1 as a is less than, equal to or greater
+// than b, or false if they are not both numbers or both strings.
+func compare(a, b interface{}) (int, bool) {
+	switch x := a.(type) {
+	case Number:
+		y, ok := b.(Number)
+		if !ok {
+			return 0, false
+		}
+		return compareNumbers(x, y)
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// parseQuery parses a query into steps. A leading "$" is ignored.
+func parseQuery(query string) ([]*step, os.Error) {
+	var steps []*step
+	var key bytes.Buffer
+	escaped := false
+	descend := false
+	add := func(s *step) {
+		s.descend = descend
+		descend = false
+		steps = append(steps, s)
+	}
+	flush := func() {
+		if key.Len() > 0 {
+			if key.String() == "*" && !escaped {
+				add(&step{kind: stepAll})
+			} else {
+				add(&step{kind: stepKey, seg: segment{key.String(), false}})
+			}
+			key.Reset()
+		}
+		escaped = false
+	}
+	i := 0
+	if strings.HasPrefix(query, "$") {
+		i++
+	}
+	for i < len(query) {
+		c := query[i]
+		switch {
+		case strings.HasPrefix(query[i:], PropNameDelim+PropNameDelim):
+			flush()
+			if descend {
+				return nil, &NameError{query, i, "unexpected '" + PropNameDelim + "'"}
+			}
+			descend = true
+			i += 2 * len(PropNameDelim)
+		case strings.HasPrefix(query[i:], PropNameDelim):
+			flush()
+			i += len(PropNameDelim)
+		case c == '\\':
+			if i+1 == len(query) {
+				return nil, &NameError{query, i, "escape at end of name"}
+			}
+			key.WriteByte(query[i+1])
+			escaped = true
+			i += 2
+		case c == '[':
+			flush()
+			switch {
+			case strings.HasPrefix(query[i:], "[*]"):
+				add(&step{kind: stepAll})
+				i += 3
+			case strings.HasPrefix(query[i:], "[?("):
+				f, n, err := parseFilter(query, i)
+				if err != nil {
+					return nil, err
+				}
+				add(&step{kind: stepFilter, filter: f})
+				i = n
+			default:
+				seg, n, err := parseSelector(query, i)
+				if err != nil {
+					return nil, err
+				}
+				add(&step{kind: stepKey, seg: seg})
+				i = n
+			}
+			if i < len(query) && query[i] != '[' && !strings.HasPrefix(query[i:], PropNameDelim) {
+				return nil, &NameError{query, i, "expected '" + PropNameDelim + "' or '[' after ']'"}
+			}
+		case c == ']':
+			return nil, &NameError{query, i, "unexpected ']'"}
+		default:
+			key.WriteByte(c)
+			i++
+		}
+	}
+	flush()
+	if descend {
+		return nil, &NameError{query, len(query), "expected a name after '" + PropNameDelim + PropNameDelim + "'"}
+	}
+	return steps, nil
+}
+
+// parseFilter parses the filter starting at query[i] and returns
+// the filter and the offset following its closing bracket.
+func parseFilter(query string, i int) (*filter, int, os.Error) {
+	start := i
+	i += len("[?(")
+	end := -1
+	var quote byte
+	for j := i; j < len(query) && end < 0; j++ {
+		switch c := query[j]; {
+		case quote != 0:
+			if c == '\\' {
+				j++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ')' && strings.HasPrefix(query[j:], ")]"):
+			end = j
+		}
+	}
+	if end < 0 {
+		return nil, 0, &NameError{query, start, "unterminated filter"}
+	}
+	for i < end && query[i] == ' ' {
+		i++
+	}
+	if i == end || query[i] != '@' {
+		return nil, 0, &NameError{query, i, "filter does not start with '@'"}
+	}
+	i++
+
+	// The path ends at the first operator character outside quotes.
+	op := end
+	quote = 0
+	for j := i; j < end && op == end; j++ {
+		switch c := query[j]; {
+		case quote != 0:
+			if c == '\\' {
+				j++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '=' || c == '!' || c == '<' || c == '>':
+			op = j
+		}
+	}
+	path, err := parsePath(strings.TrimSpace(query[i:op]))
+	if err != nil {
+		return nil, 0, &NameError{query, i, "filter path is not valid: " + err.(*NameError).Msg}
+	}
+	f := &filter{path: path}
+	if op == end {
+		return f, end + 2, nil
+	}
+
+	f.op = query[op : op+1]
+	if op+1 < end && query[op+1] == '=' {
+		f.op = query[op : op+2]
+	}
+	if f.op == "=" || f.op == "!" {
+		return nil, 0, &NameError{query, op, "unknown operator '" + f.op + "'"}
+	}
+	lit := strings.TrimSpace(query[op+len(f.op) : end])
+	var ok bool
+	f.value, ok = parseLiteral(lit)
+	if !ok {
+		return nil, 0, &NameError{query, op + len(f.op), "filter value is not a literal: " + lit}
+	}
+	return f, end + 2, nil
+}
+
+// parseLiteral parses the literal of a filter, which is a JSON value
+// other than a map or array, or a string in single quotes.
+func parseLiteral(s string) (interface{}, bool) {
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		var b bytes.Buffer
+		for i := 1; i < len(s)-1; i++ {
+			if s[i] == '\\' && i+1 < len(s)-1 {
+				i++
+			}
+			b.WriteByte(s[i])
+		}
+		return b.String(), true
+	}
+	v, err := decodeJSON([]byte(s))
+	if err != nil {
+		return nil, false
+	}
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		return nil, false
+	}
+	return v, true
+}
@@ -0,0 +1,484 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config
+
+import (
+	"os"
+	"io"
+	"fmt"
+	"sort"
+	"utf8"
+	"regexp"
+	"strings"
+	"strconv"
+)
+
+// maxRefDepth limits how many $ref are followed without moving into
+// the value, so that a reference cycle in a schema cannot loop forever.
+const maxRefDepth = 32
+
+// Schema is a JSON Schema used to validate properties. The supported
+// keywords are a subset of draft 2020-12: type, enum, required,
+// properties, additionalProperties, minProperties, maxProperties, items,
+// minItems, maxItems, minimum, maximum, exclusiveMinimum,
+// exclusiveMaximum, minLength, maxLength, pattern and $ref to a JSON
+// pointer within the same document, such as "#/$defs/port". Other
+// keywords are ignored.
+type Schema struct {
+	root     interface{}
+	patterns map[string]*regexp.Regexp
+	refs     map[string]bool // the references checked by NewSchema
+}
+
+// SchemaError is returned by Validate and lists every property
+// that does not match the schema.
+type SchemaError struct {
+	Errors []*FieldError
+}
+
+func (e *SchemaError) String() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.String()
+	}
+	return "properties do not match schema: " + strings.Join(msgs, "; ")
+}
+
+// ReadSchema decodes a JSON Schema from JSON data.
+func ReadSchema(r io.Reader) (*Schema, os.Error) {
+	p, err := ReadProperties(r)
+	if err != nil {
+		return nil, err
+	}
+	return NewSchema(p)
+}
+
+// NewSchema creates a Schema from properties, so that a schema may be
+// read in any of the formats supported by ReadConfigFile. An error is
+// returned if a keyword has a value of the wrong type, a pattern is
+// not a valid regular expression or a $ref cannot be resolved.
+func NewSchema(p *Properties) (*Schema, os.Error) {
+	p.rlock()
+	root, err := normalize(p.root)
+	p.runlock()
+	if err != nil {
+		return nil, err
+	}
+	s := &Schema{root: root, patterns: make(map[string]*regexp.Regexp), refs: make(map[string]bool)}
+	err = s.check(root)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Validate checks the properties against a schema and returns a
+// *SchemaError listing every violation, named by the full property name.
+// Values from the environment overlay are not validated.
+func (p *Properties) Validate(s *Schema) os.Error {
+	p.rlock()
+	defer p.runlock()
+	v := &validator{s: s}
+	v.validate(s.root, p.root, joinPath(p.path, nil), 0)
+	if len(v.errs) > 0 {
+		return &SchemaError{v.errs}
+	}
+	return nil
+}
+
+// check verifies the keywords of a schema, compiling its patterns.
+func (s *Schema) check(schema interface{}) os.Error {
+	m, ok := schema.(map[string]interface{})
+	if !ok {
+		if _, ok := schema.(bool); ok {
+			return nil
+		}
+		return os.NewError(fmt.Sprint("schema is not of type 'object' or 'boolean': ", typeName(schema)))
+	}
+	for _, key := range sortedKeys(m) {
+		kw := m[key]
+		var err os.Error
+		switch key {
+		case "$ref":
+			ref, ok := kw.(string)
+			if !ok {
+				return keywordError(key, "string")
+			}
+			err = s.checkRef(ref)
+		case "type":
+			err = checkTypes(kw)
+		case "enum":
+			if _, ok := kw.([]interface{}); !ok {
+				return keywordError(key, "array")
+			}
+		case "required":
+			a, ok := kw.([]interface{})
+			if !ok {
+				return keywordError(key, "array")
+			}
+			for _, elem := range a {
+				if _, ok := elem.(string); !ok {
+					return keywordError(key, "array of strings")
+				}
+			}
+		case "properties", "$defs", "definitions":
+			sub, ok := kw.(map[string]interface{})
+			if !ok {
+				return keywordError(key, "object")
+			}
+			for _, name := range sortedKeys(sub) {
+				err = s.check(sub[name])
+				if err != nil {
+					return err
+				}
+			}
+		case "additionalProperties", "items":
+			err = s.check(kw)
+		case "minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum":
+			if _, ok := kw.(Number); !ok {
+				return keywordError(key, "number")
+			}
+		case "minLength", "maxLength", "minItems", "maxItems", "minProperties", "maxProperties":
+			n, ok := kw.(Number)
+			if !ok {
+				return keywordError(key, "integer")
+			}
+			if _, err := n.Int64(); err != nil {
+				return keywordError(key, "integer")
+			}
+		case "pattern":
+			pat, ok := kw.(string)
+			if !ok {
+				return keywordError(key, "string")
+			}
+			re, err := regexp.Compile(pat)
+			if err != nil {
+				return os.NewError(fmt.Sprint("schema pattern is not valid: ", pat, ": ", err))
+			}
+			s.patterns[pat] = re
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkTypes(kw interface{}) os.Error {
+	types, ok := schemaTypes(kw)
+	if !ok {
+		return keywordError("type", "string or array of strings")
+	}
+	for _, t := range types {
+		switch t {
+		case "null", "boolean", "object", "array", "number", "integer", "string":
+		default:
+			return os.NewError("schema type is not valid: " + t)
+		}
+	}
+	return nil
+}
+
+func keywordError(key, typ string) os.Error {
+	return os.NewError("schema keyword '" + key + "' is not of type '" + typ + "'.")
+}
+
+// schemaTypes returns the value of a type keyword as a list.
+func schemaTypes(kw interface{}) ([]string, bool) {
+	switch t := kw.(type) {
+	case string:
+		return []string{t}, true
+	case []interface{}:
+		types := make([]string, len(t))
+		for i, elem := range t {
+			s, ok := elem.(string)
+			if !ok {
+				return nil, false
+			}
+			types[i] = s
+		}
+		return types, true
+	}
+	return nil, false
+}
+
+// checkRef verifies the schema referred to by a $ref, so that the
+// patterns of a schema reachable only through a reference are
+// compiled. Each reference is checked once.
+func (s *Schema) checkRef(ref string) os.Error {
+	if s.refs[ref] {
+		return nil
+	}
+	s.refs[ref] = true
+	target, err := s.resolve(ref)
+	if err != nil {
+		return err
+	}
+	return s.check(target)
+}
+
+// resolve returns the schema referred to by a JSON pointer
+// fragment, such as "#" or "#/$defs/port".
+func (s *Schema) resolve(ref string) (interface{}, os.Error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, os.NewError("schema reference is not within the document: " + ref)
+	}
+	v := s.root
+	if ref == "#" {
+		return v, nil
+	}
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, os.NewError("schema reference cannot be resolved: " + ref)
+	}
+	for _, tok := range strings.Split(ref[2:], "/") {
+		tok = strings.Replace(strings.Replace(tok, "~1", "/", -1), "~0", "~", -1)
+		switch c := v.(type) {
+		case map[string]interface{}:
+			elem, ok := c[tok]
+			if !ok {
+				return nil, os.NewError("schema reference cannot be resolved: " + ref)
+			}
+			v = elem
+		case []interface{}:
+			idx, err := strconv.Atoi(tok)
+			if err != nil || idx < 0 || idx >= len(c) {
+				return nil, os.NewError("schema reference cannot be resolved: " + ref)
+			}
+			v = c[idx]
+		default:
+			return nil, os.NewError("schema reference cannot be resolved: " + ref)
+		}
+	}
+	return v, nil
+}
+
+// validator collects the errors found while validating a property tree.
+type validator struct {
+	s    *Schema
+	errs []*FieldError
+}
+
+func (v *validator) fail(path []segment, msg string) {
+	v.errs = append(v.errs, &FieldError{formatPath(path), msg})
+}
+
+func (v *validator) validate(schema, prop interface{}, path []segment, depth int) {
+	m, ok := schema.(map[string]interface{})
+	if !ok {
+		if b, ok := schema.(bool); ok && !b {
+			v.fail(path, "property is not allowed.")
+		}
+		return
+	}
+
+	if ref, ok := m["$ref"].(string); ok {
+		if depth >= maxRefDepth {
+			v.fail(path, "schema reference is too deep: "+ref)
+			return
+		}
+		target, err := v.s.resolve(ref)
+		if err != nil {
+			v.fail(path, err.String())
+			return
+		}
+		v.validate(target, prop, path, depth+1)
+	}
+
+	if kw, ok := m["type"]; ok {
+		types, _ := schemaTypes(kw)
+		match := false
+		for _, t := range types {
+			if isType(prop, t) {
+				match = true
+				break
+			}
+		}
+		if !match {
+			v.fail(path, fmt.Sprintf("value of type '%s' is not of type '%s'.", jsonType(prop), strings.Join(types, "' or '")))
+			return
+		}
+	}
+
+	if kw, ok := m["enum"].([]interface{}); ok {
+		match := false
+		for _, elem := range kw {
+			if sameValue(elem, prop) {
+				match = true
+				break
+			}
+		}
+		if !match {
+			v.fail(path, fmt.Sprint("value is not one of the allowed values: ", enumString(kw)))
+		}
+	}
+
+	switch t := prop.(type) {
+	case Number:
+		v.number(m, t, path)
+	case string:
+		v.string(m, t, path)
+	case []interface{}:
+		v.array(m, t, path)
+	case map[string]interface{}:
+		v.object(m, t, path)
+	}
+}
+
+func (v *validator) number(m map[string]interface{}, n Number, path []segment) {
+	bounds := []struct {
+		key string
+		ok  func(c int) bool
+		msg string
+	}{
+		{"minimum", func(c int) bool { return c >= 0 }, "less than"},
+		{"maximum", func(c int) bool { return c <= 0 }, "greater than"},
+		{"exclusiveMinimum", func(c int) bool { return c > 0 }, "less than or equal to"},
+		{"exclusiveMaximum", func(c int) bool { return c < 0 }, "greater than or equal to"},
+	}
+	for _, b := range bounds {
+		limit, ok := m[b.key].(Number)
+		if !ok {
+			continue
+		}
+		c, ok := compareNumbers(n, limit)
+		if ok && !b.ok(c) {
+			v.fail(path, fmt.Sprint("value ", n, " is ", b.msg, " ", limit, "."))
+		}
+	}
+}
+
+func (v *validator) string(m map[string]interface{}, s string, path []segment) {
+	n := int64(utf8.RuneCountInString(s))
+	if min, ok := limit(m, "minLength"); ok && n < min {
+		v.fail(path, fmt.Sprint("value is shorter than ", min, " characters."))
+	}
+	if max, ok := limit(m, "maxLength"); ok && n > max {
+		v.fail(path, fmt.Sprint("value is longer than ", max, " characters."))
+	}
+	if pat, ok := m["pattern"].(string); ok {
+		if re := v.s.patterns[pat]; re != nil && !re.MatchString(s) {
+			v.fail(path, fmt.Sprint("value does not match pattern: ", pat))
+		}
+	}
+}
+
+func (v *validator) array(m map[string]interface{}, a []interface{}, path []segment) {
+	n := int64(len(a))
+	if min, ok := limit(m, "minItems"); ok && n < min {
+		v.fail(path, fmt.Sprint("array has fewer than ", min, " items."))
+	}
+	if max, ok := limit(m, "maxItems"); ok && n > max {
+		v.fail(path, fmt.Sprint("array has more than ", max, " items."))
+	}
+	if items, ok := m["items"]; ok {
+		for i, elem := range a {
+			v.validate(items, elem, appendPath(path, segment{strconv.Itoa(i), true}), 0)
+		}
+	}
+}
+
+func (v *validator) object(m map[string]interface{}, obj map[string]interface{}, path []segment) {
+	n := int64(len(obj))
+	if min, ok := limit(m, "minProperties"); ok && n < min {
+		v.fail(path, fmt.Sprint("map has fewer than ", min, " properties."))
+	}
+	if max, ok := limit(m, "maxProperties"); ok && n > max {
+		v.fail(path, fmt.Sprint("map has more than ", max, " properties."))
+	}
+	if required, ok := m["required"].([]interface{}); ok {
+		for _, elem := range required {
+			key, _ := elem.(string)
+			if _, ok := obj[key]; !ok {
+				v.fail(appendPath(path, segment{key, false}), "property is required.")
+			}
+		}
+	}
+	props, _ := m["properties"].(map[string]interface{})
+	additional, hasAdditional := m["additionalProperties"]
+	for _, key := range sortedKeys(obj) {
+		elemPath := appendPath(path, segment{key, false})
+		if sub, ok := props[key]; ok {
+			v.validate(sub, obj[key], elemPath, 0)
+		} else if hasAdditional {
+			v.validate(additional, obj[key], elemPath, 0)
+		}
+	}
+}
+
+// limit returns the value of an integer keyword.
+func limit(m map[string]interface{}, key string) (int64, bool) {
+	n, ok := m[key].(Number)
+	if !ok {
+		return 0, false
+	}
+	i, err := n.Int64()
+	return i, err == nil
+}
+
+// isType reports whether a property value is of a JSON Schema type.
+func isType(prop interface{}, t string) bool {
+	switch v := prop.(type) {
+	case nil:
+		return t == "null"
+	case bool:
+		return t == "boolean"
+	case string:
+		return t == "string"
+	case Number:
+		if t == "integer" {
+			_, err := v.integer()
+			return err == nil
+		}
+		return t == "number"
+	case map[string]interface{}:
+		return t == "object"
+	case []interface{}:
+		return t == "array"
+	}
+	return false
+}
+
+// jsonType returns the JSON Schema type name of a property value.
+func jsonType(prop interface{}) string {
+	switch prop.(type) {
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	}
+	return typeName(prop)
+}
+
+// sameValue reports whether two values are equal, comparing
+// numbers by value so that 1 and 1.0 are the same.
+func sameValue(a, b interface{}) bool {
+	if x, ok := a.(Number); ok {
+		if y, ok := b.(Number); ok {
+			c, ok := compareNumbers(x, y)
+			return ok && c == 0
+		}
+	}
+	return equal(a, b)
+}
+
+func enumString(values []interface{}) string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		if str, ok := v.(string); ok {
+			s[i] = strconv.Quote(str)
+		} else {
+			s[i] = fmt.Sprint(v)
+		}
+	}
+	return strings.Join(s, ", ")
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
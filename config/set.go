@@ -0,0 +1,303 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config
+
+import (
+	"os"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// maxArrayGap limits how far past the end of an array Set may store
+// an element, so that a large index cannot allocate a huge array.
+const maxArrayGap = 1024
+
+// Set stores a property value, creating intermediate maps and arrays
+// that do not exist and growing arrays as needed, by at most 1024 nil
+// elements between the end of an array and the index. A missing segment
+// written as an index, "name[n]" or an integer name, is created as an
+// array, any other missing segment is created as a map. Calling Set
+// without a name replaces the root property value.
+func (p *Properties) Set(value interface{}, name ...interface{}) os.Error {
+	path, err := parseName(name...)
+	if err != nil {
+		return err
+	}
+	v, err := normalize(value)
+	if err != nil {
+		return err
+	}
+	p.wlock()
+	defer p.wunlock()
+	t, i := p.tree()
+	full := joinPath(p.path, path)
+	resolved, _ := resolveIndices(t.root, full[i:])
+	root, err := set(t.root, full, i, v)
+	if err != nil {
+		return err
+	}
+	t.root = root
+	p.refresh()
+	p.origins.set(joinPath(full[:i], resolved), "")
+	return nil
+}
+
+// Delete removes a property value from its containing map or array.
+// Elements following a deleted array element are shifted down.
+func (p *Properties) Delete(name ...interface{}) os.Error {
+	path, err := parseName(name...)
+	if err != nil {
+		return err
+	}
+	if len(path) == 0 {
+		return os.NewError("property name is required, cannot delete root property.")
+	}
+	p.wlock()
+	defer p.wunlock()
+	t, i := p.tree()
+	full := joinPath(p.path, path)
+	resolved, elem := resolveIndices(t.root, full[i:])
+	root, err := del(t.root, full, i)
+	if err != nil {
+		return err
+	}
+	t.root = root
+	p.refresh()
+	resolved = joinPath(full[:i], resolved)
+	if elem {
+		p.origins.remove(resolved)
+	} else {
+		p.origins.forget(resolved)
+	}
+	return nil
+}
+
+// tree returns the properties holding the tree that changes to p are
+// made in, and the number of segments of p.path above its root. Sub
+// properties are changed in the top level tree, so that the change
+// is seen there and changes made there are not undone, unless their
+// path has a slice, whose values are a copy.
+func (p *Properties) tree() (*Properties, int) {
+	top := p.topLevel()
+	if top == p || hasSlice(p.path) {
+		return p, len(p.path)
+	}
+	return top, 0
+}
+
+// refresh reads the values of sub properties again from the top level
+// tree after a change made there. It is called with the lock held.
+func (p *Properties) refresh() {
+	if t, _ := p.tree(); t != p {
+		p.root, _ = lookup(t.root, nil, p.path)
+	}
+}
+
+// store replaces the property values of p with root, storing them at
+// the path of sub properties in the top level tree. It is called with
+// the lock held.
+func (p *Properties) store(root interface{}) os.Error {
+	t, _ := p.tree()
+	if t != p {
+		r, err := set(t.root, p.path, 0, root)
+		if err != nil {
+			return err
+		}
+		t.root = r
+	}
+	p.root = root
+	return nil
+}
+
+// hasSlice reports whether a segment of path is a slice.
+func hasSlice(path []segment) bool {
+	for _, seg := range path {
+		if isSlice(seg) {
+			return true
+		}
+	}
+	return false
+}
+
+// set stores value at path[i:] below cur and returns the updated cur,
+// which differs from the original if it was created or grown. The path
+// is the full path, used to name the property in errors.
+func set(cur interface{}, path []segment, i int, value interface{}) (interface{}, os.Error) {
+	if i == len(path) {
+		return value, nil
+	}
+	seg := path[i]
+	if cur == nil {
+		if seg.index {
+			cur = []interface{}{}
+		} else {
+			cur = make(map[string]interface{})
+		}
+	}
+	switch v := cur.(type) {
+	case map[string]interface{}:
+		child, err := set(v[seg.key], path, i+1, value)
+		if err != nil {
+			return nil, err
+		}
+		v[seg.key] = child
+		return v, nil
+	case []interface{}:
+		idx, err := elementIndex(v, path, i)
+		if err != nil {
+			return nil, err
+		}
+		for len(v) <= idx {
+			v = append(v, nil)
+		}
+		child, err := set(v[idx], path, i+1, value)
+		if err != nil {
+			return nil, err
+		}
+		v[idx] = child
+		return v, nil
+	}
+	return nil, containerMismatch(path, i, cur)
+}
+
+// del removes the value at path[i:] below cur and returns the updated
+// cur. The path is the full path, used to name the property in errors.
+func del(cur interface{}, path []segment, i int) (interface{}, os.Error) {
+	seg := path[i]
+	last := i == len(path)-1
+	switch v := cur.(type) {
+	case map[string]interface{}:
+		child, ok := v[seg.key]
+		if !ok {
+			return nil, &NotFoundError{formatPath(path), formatPath(path[:i+1])}
+		}
+		if last {
+			v[seg.key] = nil, false
+			return v, nil
+		}
+		child, err := del(child, path, i+1)
+		if err != nil {
+			return nil, err
+		}
+		v[seg.key] = child
+		return v, nil
+	case []interface{}:
+		idx, err := elementIndex(v, path, i)
+		if err != nil {
+			return nil, err
+		}
+		if idx >= len(v) {
+			return nil, &IndexOutOfRangeError{formatPath(path), formatPath(path[:i+1]), int64(idx), len(v)}
+		}
+		if last {
+			copy(v[idx:], v[idx+1:])
+			return v[:len(v)-1], nil
+		}
+		child, err := del(v[idx], path, i+1)
+		if err != nil {
+			return nil, err
+		}
+		v[idx] = child
+		return v, nil
+	}
+	return nil, containerMismatch(path, i, cur)
+}
+
+// elementIndex returns the index in the array v selected by path[i],
+// which counts from the end of the array if negative. The index may be
+// past the end of the array, by at most maxArrayGap, which set grows
+// to hold it.
+func elementIndex(v []interface{}, path []segment, i int) (int, os.Error) {
+	if isSlice(path[i]) {
+		return 0, os.NewError("array slice cannot be modified: " + formatPath(path[:i+1]))
+	}
+	idx, ok := arrayIndex(path[i].key, len(v))
+	if !ok {
+		return 0, &TypeMismatchError{formatPath(path), formatPath(path[:i]), "map", "array"}
+	}
+	if idx < 0 || idx > int64(len(v))+maxArrayGap {
+		n, _ := strconv.Atoi64(path[i].key)
+		return 0, &IndexOutOfRangeError{formatPath(path), formatPath(path[:i+1]), n, len(v)}
+	}
+	return int(idx), nil
+}
+
+// containerMismatch returns the error for a value at path[:i] that is
+// not a map, or an array if path[i] is an index, continued by path[i].
+func containerMismatch(path []segment, i int, cur interface{}) os.Error {
+	expected := "map"
+	if path[i].index {
+		expected = "map or array"
+	}
+	return &TypeMismatchError{formatPath(path), formatPath(path[:i]), expected, typeName(cur)}
+}
+
+// normalize converts a Go value into the representation used
+// by the property tree, the same as produced by decoding JSON.
+func normalize(value interface{}) (interface{}, os.Error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case bool:
+		return v, nil
+	case string:
+		return v, nil
+	case Number:
+		return v, nil
+	case *Properties:
+		return normalize(v.root)
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			n, err := normalize(elem)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = n
+		}
+		return m, nil
+	case []interface{}:
+		a := make([]interface{}, len(v))
+		for idx, elem := range v {
+			n, err := normalize(elem)
+			if err != nil {
+				return nil, err
+			}
+			a[idx] = n
+		}
+		return a, nil
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Number(strconv.Itoa64(rv.Int())), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return Number(strconv.Uitoa64(rv.Uint())), nil
+	case reflect.Float32:
+		return Number(strconv.Ftoa32(float32(rv.Float()), 'g', -1)), nil
+	case reflect.Float64:
+		return Number(strconv.Ftoa64(rv.Float(), 'g', -1)), nil
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Slice, reflect.Array:
+		a := make([]interface{}, rv.Len())
+		for idx := range a {
+			a[idx] = rv.Index(idx).Interface()
+		}
+		return normalize(a)
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			m := make(map[string]interface{}, rv.Len())
+			for _, key := range rv.MapKeys() {
+				m[key.String()] = rv.MapIndex(key).Interface()
+			}
+			return normalize(m)
+		}
+	}
+	return nil, os.NewError(fmt.Sprint("value cannot be stored as property from type: ", reflect.TypeOf(value)))
+}
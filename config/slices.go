@@ -0,0 +1,267 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config
+
+import (
+	"os"
+	"strings"
+	"strconv"
+)
+
+// Strings retrieves an array of strings property value or an error if
+// not found. An error is also returned for the first element that is
+// not a string. A value from the environment is split on commas.
+func (p *Properties) Strings(name ...interface{}) ([]string, os.Error) {
+	a, err := p.array(name)
+	if err != nil {
+		return nil, err
+	}
+	v := make([]string, len(a))
+	for i, elem := range a {
+		var ok bool
+		v[i], ok = p.toString(elem)
+		if !ok {
+			return nil, p.elementMismatch(name, index(i), "string", elem)
+		}
+	}
+	return v, nil
+}
+
+// StringsDefault retrieves an array of strings property value or the specified default.
+func (p *Properties) StringsDefault(dflt []string, name ...interface{}) []string {
+	v, err := p.Strings(name...)
+	if err != nil {
+		p.defaultError(name, err)
+		return dflt
+	}
+	return v
+}
+
+// Int64s retrieves an array of int64 property value or an error if not
+// found. An error is also returned for the first element that is not
+// an integer or is out of range.
+func (p *Properties) Int64s(name ...interface{}) ([]int64, os.Error) {
+	a, err := p.array(name)
+	if err != nil {
+		return nil, err
+	}
+	v := make([]int64, len(a))
+	for i, elem := range a {
+		n, ok := p.toNumber(elem)
+		if !ok {
+			return nil, p.elementMismatch(name, index(i), "int64", elem)
+		}
+		v[i], err = n.Int64()
+		if err != nil {
+			return nil, p.invalid(name, err, index(i))
+		}
+	}
+	return v, nil
+}
+
+// Int64sDefault retrieves an array of int64 property value or the specified default.
+func (p *Properties) Int64sDefault(dflt []int64, name ...interface{}) []int64 {
+	v, err := p.Int64s(name...)
+	if err != nil {
+		p.defaultError(name, err)
+		return dflt
+	}
+	return v
+}
+
+// Float64s retrieves an array of float64 property value or an error if
+// not found. An error is also returned for the first element that is
+// not a number.
+func (p *Properties) Float64s(name ...interface{}) ([]float64, os.Error) {
+	a, err := p.array(name)
+	if err != nil {
+		return nil, err
+	}
+	v := make([]float64, len(a))
+	for i, elem := range a {
+		n, ok := p.toNumber(elem)
+		if !ok {
+			return nil, p.elementMismatch(name, index(i), "float64", elem)
+		}
+		v[i], err = n.Float64()
+		if err != nil {
+			return nil, p.invalid(name, err, index(i))
+		}
+	}
+	return v, nil
+}
+
+// Float64sDefault retrieves an array of float64 property value or the specified default.
+func (p *Properties) Float64sDefault(dflt []float64, name ...interface{}) []float64 {
+	v, err := p.Float64s(name...)
+	if err != nil {
+		p.defaultError(name, err)
+		return dflt
+	}
+	return v
+}
+
+// Bools retrieves an array of boolean property value or an error if not
+// found. An error is also returned for the first element that is not a
+// boolean.
+func (p *Properties) Bools(name ...interface{}) ([]bool, os.Error) {
+	a, err := p.array(name)
+	if err != nil {
+		return nil, err
+	}
+	v := make([]bool, len(a))
+	for i, elem := range a {
+		var ok bool
+		v[i], ok = p.toBool(elem)
+		if !ok {
+			return nil, p.elementMismatch(name, index(i), "bool", elem)
+		}
+	}
+	return v, nil
+}
+
+// BoolsDefault retrieves an array of boolean property value or the specified default.
+func (p *Properties) BoolsDefault(dflt []bool, name ...interface{}) []bool {
+	v, err := p.Bools(name...)
+	if err != nil {
+		p.defaultError(name, err)
+		return dflt
+	}
+	return v
+}
+
+// StringMap retrieves a map of strings property value or an error if
+// not found. An error is also returned for the first key, in sorted
+// order, whose value is not a string.
+func (p *Properties) StringMap(name ...interface{}) (map[string]string, os.Error) {
+	path, err := parseName(name...)
+	if err != nil {
+		return nil, err
+	}
+	prop, err := p.lookup(path)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := prop.(map[string]interface{})
+	if !ok {
+		return nil, p.mismatch(name, "map", prop)
+	}
+	v := make(map[string]string, len(m))
+	for _, key := range sortedKeys(m) {
+		elem := m[key]
+		if p.interp {
+			elem, err = p.interpolate(elem, appendPath(path, segment{key, false}))
+			if err != nil {
+				return nil, err
+			}
+		}
+		var ok bool
+		v[key], ok = p.toString(elem)
+		if !ok {
+			return nil, p.elementMismatch(name, segment{key, false}, "string", elem)
+		}
+	}
+	return v, nil
+}
+
+// StringMapDefault retrieves a map of strings property value or the specified default.
+func (p *Properties) StringMapDefault(dflt map[string]string, name ...interface{}) map[string]string {
+	v, err := p.StringMap(name...)
+	if err != nil {
+		p.defaultError(name, err)
+		return dflt
+	}
+	return v
+}
+
+// PropertiesMap retrieves a map property value as Properties for each
+// key, or an error if not found.
+func (p *Properties) PropertiesMap(name ...interface{}) (map[string]*Properties, os.Error) {
+	path, err := parseName(name...)
+	if err != nil {
+		return nil, err
+	}
+	prop, err := p.lookup(path)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := prop.(map[string]interface{})
+	if !ok {
+		return nil, p.mismatch(name, "map", prop)
+	}
+	p.rlock()
+	defer p.runlock()
+	v := make(map[string]*Properties, len(m))
+	for key, elem := range m {
+		v[key] = p.sub(elem, appendPath(path, segment{key, false}))
+	}
+	return v, nil
+}
+
+// PropertiesMapDefault retrieves a map property value as Properties
+// for each key or the specified default.
+func (p *Properties) PropertiesMapDefault(dflt map[string]*Properties, name ...interface{}) map[string]*Properties {
+	v, err := p.PropertiesMap(name...)
+	if err != nil {
+		p.defaultError(name, err)
+		return dflt
+	}
+	return v
+}
+
+// array retrieves an array property value, expanding references in
+// the elements if interpolation is enabled. A value from the
+// environment is split on commas into elements of type envValue.
+func (p *Properties) array(name []interface{}) ([]interface{}, os.Error) {
+	path, err := parseName(name...)
+	if err != nil {
+		return nil, err
+	}
+	prop, err := p.lookup(path)
+	if err != nil {
+		return nil, err
+	}
+	if p.interp {
+		prop, err = p.interpolate(prop, path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	switch v := prop.(type) {
+	case []interface{}:
+		if !p.interp {
+			return v, nil
+		}
+		a := make([]interface{}, len(v))
+		for i, elem := range v {
+			a[i], err = p.interpolate(elem, appendPath(path, segment{strconv.Itoa(i), true}))
+			if err != nil {
+				return nil, err
+			}
+		}
+		return a, nil
+	case envValue:
+		return envArray(v), nil
+	}
+	return nil, p.mismatch(name, "array", prop)
+}
+
+// envArray splits a value from the environment on commas into
+// elements of type envValue.
+func envArray(v envValue) []interface{} {
+	if v == "" {
+		return []interface{}{}
+	}
+	parts := strings.Split(string(v), ",")
+	a := make([]interface{}, len(parts))
+	for i, s := range parts {
+		a[i] = envValue(strings.TrimSpace(s))
+	}
+	return a
+}
+
+// index returns the segment of the array element at i.
+func index(i int) segment {
+	return segment{strconv.Itoa(i), true}
+}
@@ -0,0 +1,41 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config
+
+import (
+	"os"
+)
+
+// SetStrict enables strict mode for the Default getters, such as
+// BoolDefault and Int64Default, or disables it if handler is nil. The
+// getters always return the default when a property is not found. In
+// strict mode, when a property is found but its value cannot be used,
+// for example the string "8080" for Int64Default, handler is called
+// with the property name and the error before the default is returned.
+// The handler may log the error, record it to be returned later, or
+// panic. Properties retrieved from p after it is called share the mode.
+func (p *Properties) SetStrict(handler func(name string, err os.Error)) {
+	p.strict = handler
+}
+
+// PanicOnInvalid is a handler for SetStrict that panics with the
+// error for a property value that cannot be used.
+func PanicOnInvalid(name string, err os.Error) {
+	panic(err)
+}
+
+// defaultError is called by the Default getters when the property
+// named by name could not be retrieved, and passes err to the strict
+// mode handler unless the property was not found.
+func (p *Properties) defaultError(name []interface{}, err os.Error) {
+	if p.strict == nil || IsNotFound(err) {
+		return
+	}
+	path, perr := parseName(name...)
+	if perr != nil {
+		p.strict("", err)
+		return
+	}
+	p.strict(formatPath(joinPath(p.path, path)), err)
+}
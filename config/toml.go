@@ -0,0 +1,548 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config
+
+import (
+	"os"
+	"io"
+	"bytes"
+	"strings"
+	"strconv"
+	"io/ioutil"
+)
+
+// ReadTOML decodes TOML data and stores it in a Properties structure.
+// Tables and dotted keys become nested maps and arrays of tables become
+// arrays of maps. Dates and times are stored as strings.
+func ReadTOML(r io.Reader) (*Properties, os.Error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	t := &tomlParser{data: data, defined: make(map[string]bool)}
+	root, err := t.parse()
+	if err != nil {
+		return nil, withText(err, data)
+	}
+	return &Properties{root: root}, nil
+}
+
+type tomlParser struct {
+	data    []byte
+	pos     int
+	defined map[string]bool // the tables defined by a header, by path
+}
+
+func (t *tomlParser) errorf(msg string, args ...interface{}) os.Error {
+	return syntaxErrorAt("toml", t.data, t.pos, msg, args...)
+}
+
+func (t *tomlParser) eof() bool {
+	return t.pos >= len(t.data)
+}
+
+func (t *tomlParser) peek() byte {
+	if t.eof() {
+		return 0
+	}
+	return t.data[t.pos]
+}
+
+func (t *tomlParser) next() byte {
+	c := t.peek()
+	t.pos++
+	return c
+}
+
+func (t *tomlParser) prefix(s string) bool {
+	return bytes.HasPrefix(t.data[t.pos:], []byte(s))
+}
+
+func (t *tomlParser) expect(s string) os.Error {
+	if !t.prefix(s) {
+		return t.errorf("expected '%s'.", s)
+	}
+	t.pos += len(s)
+	return nil
+}
+
+// space skips spaces and tabs.
+func (t *tomlParser) space() {
+	for c := t.peek(); c == ' ' || c == '\t'; c = t.peek() {
+		t.next()
+	}
+}
+
+// blank skips whitespace, newlines and comments.
+func (t *tomlParser) blank() {
+	for !t.eof() {
+		switch t.peek() {
+		case ' ', '\t', '\r', '\n':
+			t.next()
+		case '#':
+			for !t.eof() && t.peek() != '\n' {
+				t.next()
+			}
+		default:
+			return
+		}
+	}
+}
+
+// eol skips the rest of a line, which may only contain a comment.
+func (t *tomlParser) eol() os.Error {
+	t.space()
+	if t.peek() == '#' {
+		for !t.eof() && t.peek() != '\n' {
+			t.next()
+		}
+	}
+	if t.peek() == '\r' {
+		t.next()
+	}
+	if !t.eof() && t.peek() != '\n' {
+		return t.errorf("unexpected '%c' at end of line.", t.peek())
+	}
+	return nil
+}
+
+func (t *tomlParser) parse() (map[string]interface{}, os.Error) {
+	root := make(map[string]interface{})
+	table := root
+	for {
+		t.blank()
+		if t.eof() {
+			return root, nil
+		}
+
+		if t.peek() == '[' {
+			array := t.prefix("[[")
+			t.next()
+			if array {
+				t.next()
+			}
+			t.space()
+			keys, err := t.key()
+			if err != nil {
+				return nil, err
+			}
+			t.space()
+			if array {
+				err = t.expect("]]")
+			} else {
+				err = t.expect("]")
+			}
+			if err != nil {
+				return nil, err
+			}
+			table, err = t.table(root, keys, array)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			err := t.keyValue(table)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		err := t.eol()
+		if err != nil {
+			return nil, err
+		}
+	}
+	panic("unreachable")
+}
+
+// table returns the table named by keys, creating it if needed. A
+// table that is not an array of tables may only be defined once.
+func (t *tomlParser) table(root map[string]interface{}, keys []string, array bool) (map[string]interface{}, os.Error) {
+	m := root
+	var path string
+	for i, key := range keys {
+		last := i == len(keys)-1
+		path += "\x00" + key
+		switch v := m[key].(type) {
+		case nil:
+			table := make(map[string]interface{})
+			if last && array {
+				m[key] = []interface{}{table}
+			} else {
+				m[key] = table
+			}
+			m = table
+		case map[string]interface{}:
+			if last && array {
+				return nil, t.errorf("key '%s' is not an array of tables.", key)
+			}
+			m = v
+		case []interface{}:
+			if last && array {
+				table := make(map[string]interface{})
+				m[key] = append(v, table)
+				m = table
+				continue
+			}
+			if len(v) == 0 {
+				return nil, t.errorf("key '%s' is not a table.", key)
+			}
+			table, ok := v[len(v)-1].(map[string]interface{})
+			if !ok {
+				return nil, t.errorf("key '%s' is not a table.", key)
+			}
+			path += "\x00" + strconv.Itoa(len(v)-1)
+			m = table
+		default:
+			return nil, t.errorf("key '%s' is not a table.", key)
+		}
+	}
+	if !array {
+		if t.defined[path] {
+			return nil, t.errorf("duplicate table '%s'.", strings.Join(keys, "."))
+		}
+		t.defined[path] = true
+	}
+	return m, nil
+}
+
+// keyValue parses "key = value" and stores the value in table.
+func (t *tomlParser) keyValue(table map[string]interface{}) os.Error {
+	keys, err := t.key()
+	if err != nil {
+		return err
+	}
+	t.space()
+	err = t.expect("=")
+	if err != nil {
+		return err
+	}
+	t.space()
+	v, err := t.value()
+	if err != nil {
+		return err
+	}
+
+	m := table
+	for _, key := range keys[:len(keys)-1] {
+		switch child := m[key].(type) {
+		case nil:
+			next := make(map[string]interface{})
+			m[key] = next
+			m = next
+		case map[string]interface{}:
+			m = child
+		default:
+			return t.errorf("key '%s' is not a table.", key)
+		}
+	}
+	key := keys[len(keys)-1]
+	if _, ok := m[key]; ok {
+		return t.errorf("duplicate key '%s'.", key)
+	}
+	m[key] = v
+	return nil
+}
+
+// key parses a dotted key of bare or quoted parts.
+func (t *tomlParser) key() ([]string, os.Error) {
+	var keys []string
+	for {
+		var key string
+		switch c := t.peek(); {
+		case c == '"':
+			s, err := t.basicString()
+			if err != nil {
+				return nil, err
+			}
+			key = s
+		case c == '\'':
+			s, err := t.literalString()
+			if err != nil {
+				return nil, err
+			}
+			key = s
+		default:
+			start := t.pos
+			for c := t.peek(); isBareKey(c); c = t.peek() {
+				t.next()
+			}
+			if t.pos == start {
+				return nil, t.errorf("expected key.")
+			}
+			key = string(t.data[start:t.pos])
+		}
+		keys = append(keys, key)
+		t.space()
+		if t.peek() != '.' {
+			return keys, nil
+		}
+		t.next()
+		t.space()
+	}
+	panic("unreachable")
+}
+
+func isBareKey(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') || c == '_' || c == '-'
+}
+
+func (t *tomlParser) value() (interface{}, os.Error) {
+	switch c := t.peek(); {
+	case c == '"':
+		if t.prefix(`"""`) {
+			return t.multiBasicString()
+		}
+		return t.basicString()
+	case c == '\'':
+		if t.prefix("'''") {
+			return t.multiLiteralString()
+		}
+		return t.literalString()
+	case c == '[':
+		return t.array()
+	case c == '{':
+		return t.inlineTable()
+	case t.prefix("true"):
+		t.pos += 4
+		return true, nil
+	case t.prefix("false"):
+		t.pos += 5
+		return false, nil
+	}
+	return t.bare()
+}
+
+func (t *tomlParser) array() (interface{}, os.Error) {
+	t.next()
+	a := []interface{}{}
+	for {
+		t.blank()
+		if t.peek() == ']' {
+			t.next()
+			return a, nil
+		}
+		v, err := t.value()
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, v)
+		t.blank()
+		switch t.peek() {
+		case ',':
+			t.next()
+		case ']':
+		default:
+			return nil, t.errorf("expected ',' or ']' in array.")
+		}
+	}
+	panic("unreachable")
+}
+
+func (t *tomlParser) inlineTable() (interface{}, os.Error) {
+	t.next()
+	m := make(map[string]interface{})
+	t.space()
+	if t.peek() == '}' {
+		t.next()
+		return m, nil
+	}
+	for {
+		t.space()
+		err := t.keyValue(m)
+		if err != nil {
+			return nil, err
+		}
+		t.space()
+		switch t.next() {
+		case ',':
+		case '}':
+			return m, nil
+		default:
+			return nil, t.errorf("expected ',' or '}' in inline table.")
+		}
+	}
+	panic("unreachable")
+}
+
+// bare parses numbers, special floats and dates.
+func (t *tomlParser) bare() (interface{}, os.Error) {
+	start := t.pos
+	for !t.eof() {
+		c := t.peek()
+		if c == ' ' && isDate(t.data[start:t.pos]) && t.pos+1 < len(t.data) && '0' <= t.data[t.pos+1] && t.data[t.pos+1] <= '9' {
+			t.next()
+			continue
+		}
+		if c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == ',' || c == ']' || c == '}' || c == '#' {
+			break
+		}
+		t.next()
+	}
+	s := string(t.data[start:t.pos])
+	switch s {
+	case "inf", "+inf":
+		return Number("+Inf"), nil
+	case "-inf":
+		return Number("-Inf"), nil
+	case "nan", "+nan", "-nan":
+		return Number("NaN"), nil
+	}
+	if isDate(t.data[start:t.pos]) || (len(s) > 2 && s[2] == ':') {
+		return s, nil
+	}
+	if f, ok := number(s); ok {
+		return f, nil
+	}
+	if s == "" {
+		return nil, t.errorf("expected value.")
+	}
+	return nil, t.errorf("invalid value '%s'.", s)
+}
+
+// isDate reports whether b starts with a date of the form "2006-01-02".
+func isDate(b []byte) bool {
+	if len(b) < 10 || b[4] != '-' || b[7] != '-' {
+		return false
+	}
+	for _, i := range []int{0, 1, 2, 3, 5, 6, 8, 9} {
+		if b[i] < '0' || b[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *tomlParser) basicString() (string, os.Error) {
+	t.next()
+	var buf bytes.Buffer
+	for {
+		if t.eof() || t.peek() == '\n' {
+			return "", t.errorf("unterminated string.")
+		}
+		c := t.next()
+		switch c {
+		case '"':
+			return buf.String(), nil
+		case '\\':
+			err := t.escape(&buf)
+			if err != nil {
+				return "", err
+			}
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	panic("unreachable")
+}
+
+func (t *tomlParser) multiBasicString() (string, os.Error) {
+	t.pos += 3
+	t.newline()
+	var buf bytes.Buffer
+	for {
+		if t.eof() {
+			return "", t.errorf("unterminated string.")
+		}
+		if t.prefix(`"""`) && !t.prefix(`""""`) {
+			t.pos += 3
+			return buf.String(), nil
+		}
+		c := t.next()
+		if c != '\\' {
+			buf.WriteByte(c)
+			continue
+		}
+		// A backslash at the end of a line trims the following whitespace.
+		end := t.pos
+		for end < len(t.data) && (t.data[end] == ' ' || t.data[end] == '\t' || t.data[end] == '\r') {
+			end++
+		}
+		if end < len(t.data) && t.data[end] == '\n' {
+			t.blank()
+			continue
+		}
+		err := t.escape(&buf)
+		if err != nil {
+			return "", err
+		}
+	}
+	panic("unreachable")
+}
+
+func (t *tomlParser) literalString() (string, os.Error) {
+	t.next()
+	start := t.pos
+	for !t.eof() && t.peek() != '\'' && t.peek() != '\n' {
+		t.next()
+	}
+	if t.peek() != '\'' {
+		return "", t.errorf("unterminated string.")
+	}
+	s := string(t.data[start:t.pos])
+	t.next()
+	return s, nil
+}
+
+func (t *tomlParser) multiLiteralString() (string, os.Error) {
+	t.pos += 3
+	t.newline()
+	start := t.pos
+	for !t.eof() {
+		if t.prefix("'''") && !t.prefix("''''") {
+			s := string(t.data[start:t.pos])
+			t.pos += 3
+			return s, nil
+		}
+		t.next()
+	}
+	return "", t.errorf("unterminated string.")
+}
+
+// newline skips a newline immediately following an opening delimiter.
+func (t *tomlParser) newline() {
+	if t.prefix("\r\n") {
+		t.next()
+	}
+	if t.peek() == '\n' {
+		t.next()
+	}
+}
+
+// escape decodes an escape sequence following a backslash.
+func (t *tomlParser) escape(buf *bytes.Buffer) os.Error {
+	c := t.next()
+	switch c {
+	case 'b':
+		buf.WriteByte('\b')
+	case 't':
+		buf.WriteByte('\t')
+	case 'n':
+		buf.WriteByte('\n')
+	case 'f':
+		buf.WriteByte('\f')
+	case 'r':
+		buf.WriteByte('\r')
+	case '"':
+		buf.WriteByte('"')
+	case '\\':
+		buf.WriteByte('\\')
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		if t.pos+n > len(t.data) {
+			return t.errorf("invalid unicode escape.")
+		}
+		u, err := strconv.Btoui64(string(t.data[t.pos:t.pos+n]), 16)
+		if err != nil {
+			return t.errorf("invalid unicode escape.")
+		}
+		t.pos += n
+		buf.WriteRune(int(u))
+	default:
+		return t.errorf("invalid escape '\\%c'.", c)
+	}
+	return nil
+}
@@ -0,0 +1,277 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config
+
+import (
+	"os"
+	"math"
+	"time"
+	"strings"
+	"strconv"
+)
+
+// PropDurationUnit is the number of nanoseconds in a duration
+// property given as a plain number rather than a string like "30s".
+var PropDurationUnit int64 = 1e9
+
+// PropByteSizeUnit is the number of bytes in a byte size property
+// given as a plain number rather than a string like "512MiB".
+var PropByteSizeUnit int64 = 1
+
+// PropTimeLayouts are the layouts tried in order when parsing
+// a time property. See the time package for the layout format.
+var PropTimeLayouts = []string{time.RFC3339}
+
+var durationUnits = map[string]int64{
+	"ns": 1,
+	"us": 1e3,
+	"µs": 1e3,
+	"ms": 1e6,
+	"s":  1e9,
+	"m":  60e9,
+	"h":  3600e9,
+	"d":  86400e9,
+}
+
+var byteSizeUnits = map[string]int64{
+	"":    1,
+	"b":   1,
+	"k":   1e3,
+	"kb":  1e3,
+	"mb":  1e6,
+	"gb":  1e9,
+	"tb":  1e12,
+	"pb":  1e15,
+	"eb":  1e18,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+	"pib": 1 << 50,
+	"eib": 1 << 60,
+}
+
+// Duration retrieves a duration property value in nanoseconds or an
+// error if not found. The value is a string of decimal numbers, each
+// with a unit suffix, such as "300ms", "1.5h" or "2h45m". Valid units
+// are "ns", "us" (or "µs"), "ms", "s", "m", "h" and "d". A plain number
+// is multiplied by PropDurationUnit.
+func (p *Properties) Duration(name ...interface{}) (int64, os.Error) {
+	prop, err := p.property(name)
+	if err != nil {
+		return 0, err
+	}
+	s, n, ok := unitValue(prop)
+	if !ok {
+		return 0, p.mismatch(name, "duration", prop)
+	}
+	if s != "" {
+		d, err := parseDuration(s)
+		if err != nil {
+			return 0, p.invalid(name, err)
+		}
+		return d, nil
+	}
+	d, err := scaleNumber(n, PropDurationUnit, "duration")
+	if err != nil {
+		return 0, p.invalid(name, err)
+	}
+	return d, nil
+}
+
+// DurationDefault retrieves a duration property value in nanoseconds
+// or the specified default.
+func (p *Properties) DurationDefault(dflt int64, name ...interface{}) int64 {
+	v, err := p.Duration(name...)
+	if err != nil {
+		p.defaultError(name, err)
+		return dflt
+	}
+	return v
+}
+
+// ByteSize retrieves a byte size property value in bytes or an error if
+// not found. The value is a number with an optional unit suffix, such as
+// "512MiB" or "1.5GB". SI units ("kB", "MB", "GB", ...) are powers of 1000
+// and IEC units ("KiB", "MiB", "GiB", ...) are powers of 1024. Units are
+// not case sensitive. A plain number is multiplied by PropByteSizeUnit.
+// Negative sizes are not valid.
+func (p *Properties) ByteSize(name ...interface{}) (int64, os.Error) {
+	prop, err := p.property(name)
+	if err != nil {
+		return 0, err
+	}
+	s, n, ok := unitValue(prop)
+	if !ok {
+		return 0, p.mismatch(name, "byte size", prop)
+	}
+	if s != "" {
+		b, err := parseByteSize(s)
+		if err != nil {
+			return 0, p.invalid(name, err)
+		}
+		return b, nil
+	}
+	if f, _ := n.Float64(); f < 0 {
+		return 0, p.invalid(name, os.NewError("byte size is not valid: "+string(n)))
+	}
+	b, err := scaleNumber(n, PropByteSizeUnit, "byte size")
+	if err != nil {
+		return 0, p.invalid(name, err)
+	}
+	return b, nil
+}
+
+// ByteSizeDefault retrieves a byte size property value in bytes
+// or the specified default.
+func (p *Properties) ByteSizeDefault(dflt int64, name ...interface{}) int64 {
+	v, err := p.ByteSize(name...)
+	if err != nil {
+		p.defaultError(name, err)
+		return dflt
+	}
+	return v
+}
+
+// Time retrieves a time property value or an error if not found.
+// The value is parsed with each of PropTimeLayouts in turn.
+func (p *Properties) Time(name ...interface{}) (*time.Time, os.Error) {
+	prop, err := p.property(name)
+	if err != nil {
+		return nil, err
+	}
+	var s string
+	switch v := prop.(type) {
+	case string:
+		s = v
+	case envValue:
+		s = string(v)
+	default:
+		return nil, p.mismatch(name, "time", prop)
+	}
+	for _, layout := range PropTimeLayouts {
+		t, err := time.Parse(layout, strings.TrimSpace(s))
+		if err == nil {
+			return t, nil
+		}
+	}
+	return nil, p.invalid(name, os.NewError("time is not valid: "+s))
+}
+
+// TimeDefault retrieves a time property value or the specified default.
+func (p *Properties) TimeDefault(dflt *time.Time, name ...interface{}) *time.Time {
+	v, err := p.Time(name...)
+	if err != nil {
+		p.defaultError(name, err)
+		return dflt
+	}
+	return v
+}
+
+// unitValue returns the string of a property value with units, or the
+// number if it is a plain number. It returns false for other values.
+func unitValue(prop interface{}) (string, Number, bool) {
+	var s string
+	switch v := prop.(type) {
+	case Number:
+		return "", v, true
+	case string:
+		s = strings.TrimSpace(v)
+	case envValue:
+		s = strings.TrimSpace(string(v))
+	default:
+		return "", "", false
+	}
+	if n, ok := number(s); ok {
+		return "", n, true
+	}
+	return s, "", true
+}
+
+// scaleNumber multiplies a number by unit, exactly if it is an integer.
+func scaleNumber(n Number, unit int64, typ string) (int64, os.Error) {
+	if i, err := n.Int64(); err == nil {
+		if i != 0 && (i*unit/unit != i || (i < 0) != (i*unit < 0)) {
+			return 0, os.NewError(typ + " is out of range: " + string(n))
+		}
+		return i * unit, nil
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return 0, err
+	}
+	return scaleFloat(f, unit, typ, string(n))
+}
+
+func scaleFloat(f float64, unit int64, typ, s string) (int64, os.Error) {
+	f = math.Floor(f*float64(unit) + 0.5)
+	if math.IsNaN(f) || f >= math.MaxInt64 || f < math.MinInt64 {
+		return 0, os.NewError(typ + " is out of range: " + s)
+	}
+	return int64(f), nil
+}
+
+// parseDuration parses a duration string such as "1h30m" or "-1.5s".
+func parseDuration(s string) (int64, os.Error) {
+	orig := s
+	neg := false
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	if s == "" {
+		return 0, os.NewError("duration is not valid: " + orig)
+	}
+	var d int64
+	for s != "" {
+		i := 0
+		for i < len(s) && (s[i] == '.' || ('0' <= s[i] && s[i] <= '9')) {
+			i++
+		}
+		j := i
+		for j < len(s) && s[j] != '.' && (s[j] < '0' || s[j] > '9') {
+			j++
+		}
+		unit, ok := durationUnits[s[i:j]]
+		if i == 0 || !ok {
+			return 0, os.NewError("duration is not valid: " + orig)
+		}
+		v, err := scaleNumber(Number(s[:i]), unit, "duration")
+		if err != nil {
+			if _, ferr := strconv.Atof64(s[:i]); ferr != nil {
+				return 0, os.NewError("duration is not valid: " + orig)
+			}
+			return 0, os.NewError("duration is out of range: " + orig)
+		}
+		if d+v < d {
+			return 0, os.NewError("duration is out of range: " + orig)
+		}
+		d += v
+		s = s[j:]
+	}
+	if neg {
+		d = -d
+	}
+	return d, nil
+}
+
+// parseByteSize parses a byte size string such as "512MiB" or "1.5 GB".
+func parseByteSize(s string) (int64, os.Error) {
+	i := 0
+	for i < len(s) && (s[i] == '.' || ('0' <= s[i] && s[i] <= '9')) {
+		i++
+	}
+	unit, ok := byteSizeUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if i == 0 || !ok {
+		return 0, os.NewError("byte size is not valid: " + s)
+	}
+	if _, err := strconv.Atof64(s[:i]); err != nil {
+		return 0, os.NewError("byte size is not valid: " + s)
+	}
+	v, err := scaleNumber(Number(s[:i]), unit, "byte size")
+	if err != nil {
+		return 0, os.NewError("byte size is out of range: " + s)
+	}
+	return v, nil
+}
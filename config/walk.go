@@ -0,0 +1,72 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config
+
+import (
+	"os"
+	"sort"
+	"strconv"
+)
+
+// Walk calls fn for every leaf property value, with its name relative
+// to p in canonical form, such as "servers[0].host", which may be passed
+// to the getters of p. The leaves are the values that are not maps or
+// arrays, and empty maps and arrays. Map keys are visited in sorted
+// order. Values are returned as by Property, from the environment
+// overlay and with references expanded, and fn may call the getters
+// of p. Walking stops at the first error returned by fn, which Walk
+// returns.
+func (p *Properties) Walk(fn func(name string, value interface{}) os.Error) os.Error {
+	var leaves []node
+	p.rlock()
+	walk(p.root, nil, func(path []segment, v interface{}) os.Error {
+		leaves = append(leaves, node{path, v})
+		return nil
+	})
+	p.runlock()
+	for _, leaf := range leaves {
+		v, err := p.found(leaf.path, leaf.value)
+		if err != nil {
+			return err
+		}
+		if err = fn(formatPath(leaf.path), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walk calls fn for every value below v that is not a non-empty map
+// or array, with the path of the value. Map keys are visited in sorted
+// order. Walking stops at the first error returned by fn.
+func walk(v interface{}, path []segment, fn func(path []segment, v interface{}) os.Error) os.Error {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if len(t) > 0 {
+			keys := make([]string, 0, len(t))
+			for key := range t {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				err := walk(t[key], appendPath(path, segment{key, false}), fn)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case []interface{}:
+		if len(t) > 0 {
+			for i, elem := range t {
+				err := walk(elem, appendPath(path, segment{strconv.Itoa(i), true}), fn)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	return fn(path, v)
+}
@@ -0,0 +1,187 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config
+
+import (
+	"os"
+	"sync"
+	"time"
+	"reflect"
+)
+
+// minWatchInterval is the shortest interval, in nanoseconds, at which
+// Watch polls a config file.
+const minWatchInterval = 1e6
+
+// Watcher polls a config file and reloads it when it changes.
+type Watcher struct {
+	// Changes receives the changed properties after each reload
+	// that changes at least one property value.
+	Changes <-chan []*Change
+
+	// Errors receives errors reading or parsing the config file.
+	// The previous property values are kept when an error occurs.
+	Errors <-chan os.Error
+
+	changes chan []*Change
+	errors  chan os.Error
+	stop    chan bool // closed by Stop
+	done    chan bool // closed when polling has stopped
+	once    sync.Once
+}
+
+// Stop stops polling the config file. No more changes
+// or errors are sent after Stop returns. Stop may be
+// called more than once.
+func (w *Watcher) Stop() {
+	w.once.Do(func() {
+		close(w.stop)
+	})
+	<-w.done
+}
+
+// Watch starts polling the config file for changes to its modification
+// time or size every interval nanoseconds. When the file has changed it
+// is reloaded as by Reload. The channels of the Watcher must be read,
+// polling is paused while a change or error is waiting to be received.
+// An interval shorter than one millisecond, including an interval that
+// is not positive, is raised to one millisecond.
+func (c *ConfigFile) Watch(interval int64) *Watcher {
+	if interval < minWatchInterval {
+		interval = minWatchInterval
+	}
+	w := &Watcher{
+		changes: make(chan []*Change),
+		errors:  make(chan os.Error),
+		stop:    make(chan bool),
+		done:    make(chan bool),
+	}
+	w.Changes = w.changes
+	w.Errors = w.errors
+	fname := c.FileName()
+	mtime, size := stat(fname)
+	go c.poll(w, interval, fname, mtime, size)
+	return w
+}
+
+func (c *ConfigFile) poll(w *Watcher, interval int64, fname string, mtime, size int64) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	defer close(w.done)
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+
+		if name := c.FileName(); name != fname {
+			fname = name
+			mtime, size = 0, -1
+		}
+		m, s := stat(fname)
+		if m == mtime && s == size {
+			continue
+		}
+		mtime, size = m, s
+
+		changes, err := c.Reload()
+		switch {
+		case err != nil:
+			select {
+			case w.errors <- err:
+			case <-w.stop:
+				return
+			}
+		case len(changes) > 0:
+			select {
+			case w.changes <- changes:
+			case <-w.stop:
+				return
+			}
+		}
+	}
+}
+
+// stat returns the modification time and size of a file,
+// or zero and -1 if the file cannot be read.
+func stat(fname string) (mtime int64, size int64) {
+	fi, err := os.Stat(fname)
+	if err != nil {
+		return 0, -1
+	}
+	return fi.Mtime_ns, fi.Size
+}
+
+// Reload reads the config file again and replaces the property values,
+// returning the properties that were added, removed or modified. If the
+// file cannot be read or parsed, or does not match the schema given to
+// ReadConfigFile, the property values are not changed. Values set by
+// command line flags replace the values read, as they did before.
+func (c *ConfigFile) Reload() ([]*Change, os.Error) {
+	p, err := readConfigFile(c.FileName())
+	if err != nil {
+		return nil, err
+	}
+	if c.schema != nil {
+		err = p.Validate(c.schema)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c.wlock()
+	defer c.wunlock()
+	p.root = c.setFlags(p.root, p.origins)
+	changes := diff(c.root, p.root, nil, nil, nil)
+	c.root = p.root
+	c.origins = p.origins
+	c.javaDoc = p.javaDoc
+	return changes, nil
+}
+
+func (p *Properties) rlock() {
+	if p.mu != nil {
+		p.mu.RLock()
+	}
+}
+
+func (p *Properties) runlock() {
+	if p.mu != nil {
+		p.mu.RUnlock()
+	}
+}
+
+func (p *Properties) wlock() {
+	if p.mu != nil {
+		p.mu.Lock()
+	}
+}
+
+func (p *Properties) wunlock() {
+	if p.mu != nil {
+		p.mu.Unlock()
+	}
+}
+
+// rlockPair read locks a and b, locking a shared mutex once and two
+// mutexes in order of their addresses, so that calls locking the same
+// properties in either order cannot deadlock with a waiting writer.
+// It returns the function that unlocks them.
+func rlockPair(a, b *Properties) func() {
+	if a.mu == b.mu {
+		a.rlock()
+		return func() { a.runlock() }
+	}
+	if reflect.ValueOf(b.mu).Pointer() < reflect.ValueOf(a.mu).Pointer() {
+		a, b = b, a
+	}
+	a.rlock()
+	b.rlock()
+	return func() {
+		b.runlock()
+		a.runlock()
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config
+
+import (
+	"os"
+	"io"
+	"fmt"
+	"json"
+	"sort"
+	"bytes"
+	"reflect"
+)
+
+// PropIndent is the string used to indent nested values by WriteProperties.
+var PropIndent = "\t"
+
+// WriteProperties encodes a Properties structure as indented JSON.
+// Map keys are written in sorted order so the output is stable.
+func WriteProperties(w io.Writer, p *Properties) os.Error {
+	var buf bytes.Buffer
+	p.rlock()
+	err := encode(&buf, p.root, "")
+	p.runlock()
+	if err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func encode(buf *bytes.Buffer, v interface{}, indent string) os.Error {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if len(t) == 0 {
+			buf.WriteString("{}")
+			return nil
+		}
+		keys := make([]string, 0, len(t))
+		for key := range t {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		buf.WriteString("{\n")
+		for i, key := range keys {
+			buf.WriteString(indent + PropIndent)
+			err := encodeScalar(buf, key)
+			if err != nil {
+				return err
+			}
+			buf.WriteString(": ")
+			err = encode(buf, t[key], indent+PropIndent)
+			if err != nil {
+				return err
+			}
+			if i < len(keys)-1 {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(indent + "}")
+	case []interface{}:
+		if len(t) == 0 {
+			buf.WriteString("[]")
+			return nil
+		}
+		buf.WriteString("[\n")
+		for i, elem := range t {
+			buf.WriteString(indent + PropIndent)
+			err := encode(buf, elem, indent+PropIndent)
+			if err != nil {
+				return err
+			}
+			if i < len(t)-1 {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(indent + "]")
+	case Number:
+		if !isJSONNumber(string(t)) {
+			return os.NewError(fmt.Sprint("property cannot be encoded as a JSON number: ", t))
+		}
+		buf.WriteString(string(t))
+	case nil, bool, string:
+		return encodeScalar(buf, t)
+	default:
+		return os.NewError(fmt.Sprint("property cannot be encoded from type: ", reflect.TypeOf(v)))
+	}
+	return nil
+}
+
+// encodeCompact encodes a property value as JSON on a single line.
+func encodeCompact(buf *bytes.Buffer, v interface{}) os.Error {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		buf.WriteByte('{')
+		for i, key := range sortedKeys(t) {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			err := encodeScalar(buf, key)
+			if err != nil {
+				return err
+			}
+			buf.WriteString(": ")
+			err = encodeCompact(buf, t[key])
+			if err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, elem := range t {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			err := encodeCompact(buf, elem)
+			if err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	default:
+		return encode(buf, v, "")
+	}
+	return nil
+}
+
+func encodeScalar(buf *bytes.Buffer, v interface{}) os.Error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	return nil
+}
@@ -0,0 +1,560 @@
+// Copyright 2011 Dylan Maxwell.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package config
+
+import (
+	"os"
+	"io"
+	"bytes"
+	"strings"
+	"strconv"
+	"io/ioutil"
+)
+
+// ReadYAML decodes YAML data and stores it in a Properties structure.
+// The commonly used subset of YAML is supported: block and flow maps
+// and sequences, plain and quoted scalars, literal and folded block
+// scalars and comments. Only the first document is read. Anchors,
+// aliases, tags and complex keys are not supported.
+func ReadYAML(r io.Reader) (*Properties, os.Error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	y, err := newYAMLParser(string(data))
+	if err != nil {
+		return nil, withText(err, data)
+	}
+	root, err := y.parse()
+	if err != nil {
+		return nil, withText(err, data)
+	}
+	return &Properties{root: root}, nil
+}
+
+// yamlLine is a line of YAML that is not blank or only a comment.
+type yamlLine struct {
+	num    int // line number, starting at 1
+	indent int
+	text   string // line without indentation
+	tab    bool   // indented with a tab, which only a block scalar allows
+}
+
+type yamlParser struct {
+	raw   []string    // all lines, for block scalars
+	lines []*yamlLine // significant lines
+	i     int         // index of the current line
+}
+
+func newYAMLParser(data string) (*yamlParser, os.Error) {
+	y := &yamlParser{raw: strings.Split(data, "\n")}
+	for n, line := range y.raw {
+		line = strings.TrimRight(line, "\r")
+		y.raw[n] = line
+		text := strings.TrimLeft(line, " ")
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		indent := len(line) - len(text)
+		tab := text[0] == '\t'
+		if tab && (strings.TrimLeft(text, " \t") == "" || strings.TrimLeft(text, " \t")[0] == '#') {
+			continue
+		}
+		if indent == 0 && (text == "---" || strings.HasPrefix(text, "--- ") || text == "...") {
+			if len(y.lines) > 0 {
+				break
+			}
+			continue
+		}
+		y.lines = append(y.lines, &yamlLine{n + 1, indent, text, tab})
+	}
+	return y, nil
+}
+
+func (y *yamlParser) done() bool {
+	return y.i >= len(y.lines)
+}
+
+func (y *yamlParser) cur() *yamlLine {
+	return y.lines[y.i]
+}
+
+func (y *yamlParser) parse() (interface{}, os.Error) {
+	if y.done() {
+		return make(map[string]interface{}), nil
+	}
+	v, err := y.node(y.cur().indent)
+	if err != nil {
+		return nil, err
+	}
+	if !y.done() {
+		return nil, syntaxError("yaml", y.cur().num, "unexpected indentation.")
+	}
+	return v, nil
+}
+
+// node parses the value starting at the current line.
+func (y *yamlParser) node(indent int) (interface{}, os.Error) {
+	l := y.cur()
+	if l.tab {
+		return nil, tabError(l)
+	}
+	if isYAMLSeqItem(l.text) {
+		return y.sequence(indent)
+	}
+	if _, _, ok, err := yamlKey(l.text, l.num); err != nil || ok {
+		if err != nil {
+			return nil, err
+		}
+		return y.mapping(indent)
+	}
+	y.i++
+	return yamlInline(l.text, l.num)
+}
+
+func (y *yamlParser) mapping(indent int) (interface{}, os.Error) {
+	m := make(map[string]interface{})
+	for !y.done() {
+		l := y.cur()
+		if l.tab {
+			return nil, tabError(l)
+		}
+		if l.indent < indent || (l.indent == indent && isYAMLSeqItem(l.text)) {
+			break
+		}
+		if l.indent > indent {
+			return nil, syntaxError("yaml", l.num, "unexpected indentation.")
+		}
+		key, rest, ok, err := yamlKey(l.text, l.num)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, syntaxError("yaml", l.num, "expected map key.")
+		}
+		if _, dup := m[key]; dup {
+			return nil, syntaxError("yaml", l.num, "duplicate key '%s'.", key)
+		}
+		y.i++
+		m[key], err = y.value(rest, indent, l.num, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (y *yamlParser) sequence(indent int) (interface{}, os.Error) {
+	a := []interface{}{}
+	for !y.done() {
+		l := y.cur()
+		if l.tab {
+			return nil, tabError(l)
+		}
+		if l.indent < indent || (l.indent == indent && !isYAMLSeqItem(l.text)) {
+			break
+		}
+		if l.indent > indent {
+			return nil, syntaxError("yaml", l.num, "unexpected indentation.")
+		}
+		rest := strings.TrimLeft(l.text[1:], " ")
+		if isYAMLSeqItem(rest) {
+			l.indent += len(l.text) - len(rest)
+			l.text = rest
+			v, err := y.sequence(l.indent)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, v)
+			continue
+		}
+		if _, _, ok, _ := yamlKey(rest, l.num); ok {
+			l.indent += len(l.text) - len(rest)
+			l.text = rest
+			v, err := y.mapping(l.indent)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, v)
+			continue
+		}
+		y.i++
+		v, err := y.value(rest, indent, l.num, false)
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, v)
+	}
+	return a, nil
+}
+
+// value parses the value following a map key or sequence item on line
+// num. A sequence may be at the same indentation as its map key.
+func (y *yamlParser) value(rest string, indent, num int, key bool) (interface{}, os.Error) {
+	s := strings.TrimSpace(rest)
+	if s == "" || s[0] == '#' {
+		if y.done() {
+			return nil, nil
+		}
+		l := y.cur()
+		if l.indent > indent || (key && l.indent == indent && isYAMLSeqItem(l.text)) {
+			return y.node(l.indent)
+		}
+		return nil, nil
+	}
+	if s[0] == '|' || s[0] == '>' {
+		return y.blockScalar(s, indent, num)
+	}
+	return yamlInline(s, num)
+}
+
+// blockScalar parses a literal '|' or folded '>' block scalar
+// with optional chomping indicator '-' or '+'.
+func (y *yamlParser) blockScalar(header string, indent, num int) (interface{}, os.Error) {
+	folded := header[0] == '>'
+	chomp := byte(0)
+	h := strings.TrimSpace(header[1:])
+	if h != "" && (h[0] == '-' || h[0] == '+') {
+		chomp = h[0]
+		h = strings.TrimSpace(h[1:])
+	}
+	if h != "" && h[0] != '#' {
+		return nil, syntaxError("yaml", num, "invalid block scalar header '%s'.", header)
+	}
+
+	var lines []string
+	bindent := -1
+	last := num
+	for n := num; n < len(y.raw); n++ {
+		line := y.raw[n]
+		text := strings.TrimLeft(line, " ")
+		if text == "" {
+			lines = append(lines, "")
+			continue
+		}
+		lindent := len(line) - len(text)
+		if bindent < 0 {
+			if lindent <= indent {
+				break
+			}
+			bindent = lindent
+		}
+		if lindent < bindent {
+			break
+		}
+		lines = append(lines, line[bindent:])
+		last = n + 1
+	}
+	for !y.done() && y.cur().num <= last {
+		y.i++
+	}
+
+	// Trailing blank lines belong to the block only when kept.
+	content := len(lines)
+	for content > 0 && lines[content-1] == "" {
+		content--
+	}
+	var buf bytes.Buffer
+	for i, line := range lines[:content] {
+		if i > 0 {
+			if folded && line != "" && lines[i-1] != "" && line[0] != ' ' && lines[i-1][0] != ' ' {
+				buf.WriteByte(' ')
+			} else {
+				buf.WriteByte('\n')
+			}
+		}
+		buf.WriteString(line)
+	}
+	switch chomp {
+	case 0:
+		if content > 0 {
+			buf.WriteByte('\n')
+		}
+	case '+':
+		if content > 0 {
+			buf.WriteByte('\n')
+		}
+		for _, line := range lines[content:] {
+			buf.WriteString(line + "\n")
+		}
+	}
+	return buf.String(), nil
+}
+
+// tabError returns the error for a line outside a block scalar
+// that is indented with a tab.
+func tabError(l *yamlLine) os.Error {
+	return syntaxError("yaml", l.num, "tabs are not allowed for indentation.")
+}
+
+func isYAMLSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// yamlKey splits a line into a map key and the rest of the line, if
+// the line starts with a plain or quoted key followed by ':'.
+func yamlKey(text string, num int) (key, rest string, ok bool, err os.Error) {
+	if text == "" {
+		return
+	}
+	if text[0] == '"' || text[0] == '\'' {
+		f := &yamlFlow{text, 0, num}
+		var v string
+		v, err = f.quoted()
+		if err != nil {
+			return
+		}
+		f.space()
+		if !f.keyEnd() {
+			return "", "", false, nil
+		}
+		return v, text[f.pos+1:], true, nil
+	}
+	if strings.IndexAny(text[:1], "[{&*!|>%@`#") >= 0 {
+		return
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '#' && i > 0 && text[i-1] == ' ' {
+			return
+		}
+		if text[i] == ':' && (i == len(text)-1 || text[i+1] == ' ') {
+			return strings.TrimSpace(text[:i]), text[i+1:], true, nil
+		}
+	}
+	return
+}
+
+// yamlInline parses a flow collection or scalar that fills the rest of a line.
+func yamlInline(s string, num int) (interface{}, os.Error) {
+	f := &yamlFlow{s, 0, num}
+	v, err := f.value(false)
+	if err != nil {
+		return nil, err
+	}
+	f.space()
+	if f.pos < len(s) && s[f.pos] != '#' {
+		return nil, syntaxError("yaml", num, "unexpected '%s'.", s[f.pos:])
+	}
+	return v, nil
+}
+
+// yamlFlow parses flow collections and scalars within a line.
+type yamlFlow struct {
+	s   string
+	pos int
+	num int
+}
+
+func (f *yamlFlow) errorf(msg string, args ...interface{}) os.Error {
+	return syntaxError("yaml", f.num, msg, args...)
+}
+
+func (f *yamlFlow) space() {
+	for f.pos < len(f.s) && f.s[f.pos] == ' ' {
+		f.pos++
+	}
+}
+
+func (f *yamlFlow) peek() byte {
+	if f.pos >= len(f.s) {
+		return 0
+	}
+	return f.s[f.pos]
+}
+
+// keyEnd reports whether the next character is a ':' ending a key.
+func (f *yamlFlow) keyEnd() bool {
+	return f.peek() == ':' && (f.pos+1 == len(f.s) || strings.IndexAny(f.s[f.pos+1:f.pos+2], " ,]}") >= 0)
+}
+
+func (f *yamlFlow) value(flow bool) (interface{}, os.Error) {
+	f.space()
+	switch f.peek() {
+	case '[':
+		return f.sequence()
+	case '{':
+		return f.mapping()
+	case '"', '\'':
+		return f.quoted()
+	case '&', '*', '!':
+		return nil, f.errorf("anchors, aliases and tags are not supported.")
+	}
+	return yamlScalar(f.plain(flow)), nil
+}
+
+func (f *yamlFlow) sequence() (interface{}, os.Error) {
+	f.pos++
+	a := []interface{}{}
+	for {
+		f.space()
+		if f.peek() == ']' {
+			f.pos++
+			return a, nil
+		}
+		v, err := f.value(true)
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, v)
+		f.space()
+		switch f.peek() {
+		case ',':
+			f.pos++
+		case ']':
+		default:
+			return nil, f.errorf("expected ',' or ']' in flow sequence.")
+		}
+	}
+	panic("unreachable")
+}
+
+func (f *yamlFlow) mapping() (interface{}, os.Error) {
+	f.pos++
+	m := make(map[string]interface{})
+	for {
+		f.space()
+		if f.peek() == '}' {
+			f.pos++
+			return m, nil
+		}
+		var key string
+		if c := f.peek(); c == '"' || c == '\'' {
+			k, err := f.quoted()
+			if err != nil {
+				return nil, err
+			}
+			key = k
+		} else {
+			key = f.plain(true)
+		}
+		f.space()
+		var v interface{}
+		if f.keyEnd() {
+			f.pos++
+			var err os.Error
+			v, err = f.value(true)
+			if err != nil {
+				return nil, err
+			}
+		}
+		m[key] = v
+		f.space()
+		switch f.peek() {
+		case ',':
+			f.pos++
+		case '}':
+		default:
+			return nil, f.errorf("expected ',' or '}' in flow map.")
+		}
+	}
+	panic("unreachable")
+}
+
+// plain returns a plain scalar, which in a flow collection
+// ends at ',', ']', '}' or a ':' ending a key.
+func (f *yamlFlow) plain(flow bool) string {
+	start := f.pos
+	for f.pos < len(f.s) {
+		c := f.s[f.pos]
+		if c == '#' && f.pos > start && f.s[f.pos-1] == ' ' {
+			break
+		}
+		if flow && (c == ',' || c == ']' || c == '}' || f.keyEnd()) {
+			break
+		}
+		f.pos++
+	}
+	return strings.TrimSpace(f.s[start:f.pos])
+}
+
+func (f *yamlFlow) quoted() (string, os.Error) {
+	q := f.s[f.pos]
+	f.pos++
+	var buf bytes.Buffer
+	for f.pos < len(f.s) {
+		c := f.s[f.pos]
+		f.pos++
+		switch {
+		case c == q && q == '\'' && f.peek() == '\'':
+			buf.WriteByte('\'')
+			f.pos++
+		case c == q:
+			return buf.String(), nil
+		case c == '\\' && q == '"':
+			err := f.escape(&buf)
+			if err != nil {
+				return "", err
+			}
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return "", f.errorf("unterminated quoted scalar.")
+}
+
+func (f *yamlFlow) escape(buf *bytes.Buffer) os.Error {
+	if f.pos >= len(f.s) {
+		return f.errorf("invalid escape at end of line.")
+	}
+	c := f.s[f.pos]
+	f.pos++
+	switch c {
+	case '0':
+		buf.WriteByte(0)
+	case 'a':
+		buf.WriteByte('\a')
+	case 'b':
+		buf.WriteByte('\b')
+	case 't', '\t':
+		buf.WriteByte('\t')
+	case 'n':
+		buf.WriteByte('\n')
+	case 'v':
+		buf.WriteByte('\v')
+	case 'f':
+		buf.WriteByte('\f')
+	case 'r':
+		buf.WriteByte('\r')
+	case 'e':
+		buf.WriteByte(0x1b)
+	case ' ', '"', '/', '\\':
+		buf.WriteByte(c)
+	case 'x', 'u', 'U':
+		n := map[byte]int{'x': 2, 'u': 4, 'U': 8}[c]
+		if f.pos+n > len(f.s) {
+			return f.errorf("invalid escape '\\%c'.", c)
+		}
+		u, err := strconv.Btoui64(f.s[f.pos:f.pos+n], 16)
+		if err != nil {
+			return f.errorf("invalid escape '\\%c'.", c)
+		}
+		f.pos += n
+		buf.WriteRune(int(u))
+	default:
+		return f.errorf("invalid escape '\\%c'.", c)
+	}
+	return nil
+}
+
+// yamlScalar resolves the type of a plain scalar.
+func yamlScalar(s string) interface{} {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF":
+		return Number("+Inf")
+	case "-.inf", "-.Inf", "-.INF":
+		return Number("-Inf")
+	case ".nan", ".NaN", ".NAN":
+		return Number("NaN")
+	}
+	if f, ok := number(s); ok {
+		return f
+	}
+	return s
+}